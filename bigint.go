@@ -0,0 +1,89 @@
+package gisp
+
+import (
+	"math"
+	"math/big"
+)
+
+// BigInt is the arbitrary precision integer type (math/big.Int).
+// Integer arithmetic is promoted to BigInt on overflow, and the results that fit in an int64 are demoted back to Integer.
+type BigInt struct {
+	value *big.Int
+}
+
+// MakeBigInt creates an integer object from a big.Int.
+// It returns an Integer if the value fits in an int64, otherwise a BigInt.
+func MakeBigInt(v *big.Int) any {
+	if v.IsInt64() {
+		return Integer{value: v.Int64()}
+	}
+
+	return BigInt{value: v}
+}
+
+func (o BigInt) String() string { return o.value.String() }
+func (o BigInt) Value() any     { return o.value }
+func (o BigInt) Int() int64     { return o.value.Int64() }
+func (o BigInt) Bool() bool     { return true }
+
+func (o BigInt) Float() float64 {
+	f, _ := new(big.Float).SetInt(o.value).Float64()
+	return f
+}
+
+func (o BigInt) Eq(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c == 0
+}
+
+func (o BigInt) Lt(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c < 0
+}
+
+func (o BigInt) Leq(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c <= 0
+}
+
+func (o BigInt) Gt(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c > 0
+}
+
+func (o BigInt) Geq(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c >= 0
+}
+
+// bigkey is the hash key for big integers
+type bigkey string
+
+// int64op executes the operation op on a and b, and returns false if the result overflows
+func int64op(op string, a, b int64) (int64, bool) {
+	switch op {
+	case "+":
+		r := a + b
+		return r, (r^a)&(r^b) >= 0
+
+	case "-":
+		r := a - b
+		return r, (a^b)&(a^r) >= 0
+
+	case "*":
+		if a == 0 || b == 0 {
+			return 0, true
+		}
+
+		r := a * b
+		return r, r/b == a && !(a == math.MinInt64 && b == -1) && !(b == math.MinInt64 && a == -1)
+
+	case "/":
+		return a / b, !(a == math.MinInt64 && b == -1)
+
+	case "%":
+		return a % b, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,75 @@
+package gisp
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Capability is a set of host resources that builtins can access.
+// An Interpreter only allows calls to the builtins that require the capabilities it was granted (see WithCapabilities).
+type Capability uint
+
+const (
+	CapFSRead  Capability = 1 << iota // read files (and the standard input)
+	CapFSWrite                        // write files
+	CapTime                           // access the clock, sleep
+	CapRandom                         // random numbers
+	CapProcess                        // access the process (environment, exit, run commands)
+	CapNetwork                        // access the network
+
+	CapNone Capability = 0
+	CapAll             = CapFSRead | CapFSWrite | CapTime | CapRandom | CapProcess | CapNetwork
+)
+
+var capnames = []string{
+	"filesystem-read",
+	"filesystem-write",
+	"time",
+	"random",
+	"process",
+	"network",
+}
+
+func (c Capability) String() string {
+	if c == CapNone {
+		return "none"
+	}
+
+	var names []string
+
+	for i, name := range capnames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(names, "|")
+}
+
+// capabilities are the capabilities required by the core builtins
+var capabilities = map[string]Capability{
+	"readfile":  CapFSRead,
+	"readlines": CapFSRead,
+	"writefile": CapFSWrite,
+	"sleep":     CapTime,
+	"rand":      CapRandom,
+}
+
+// WriteFileFS is a file system that can also write files.
+// It's required by the builtins that write files (i.e. writefile).
+type WriteFileFS interface {
+	fs.FS
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// osFS is the default file system, that gives access to all the host files
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
@@ -0,0 +1,78 @@
+package gisp
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCapabilityDenied(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		caps Capability
+	}{
+		{`(readfile "go.mod")`, CapAll &^ CapFSRead},
+		{`(readlines "go.mod")`, CapAll &^ CapFSRead},
+		{`(writefile "test.txt" "x")`, CapAll &^ CapFSWrite},
+		{`(sleep 1)`, CapNone},
+		{`(rand 10)`, CapFSRead | CapTime},
+	} {
+		it := New(WithCapabilities(c.caps))
+
+		if ret, err := evalContext(t, it, c.src, time.Second); !errors.Is(err, ErrPermission) {
+			t.Errorf("%v: expected %v, got %v %v", c.src, ErrPermission, ret, err)
+		}
+	}
+
+	// the other builtins are allowed
+	it := New(WithCapabilities(CapNone))
+
+	if ret, err := evalContext(t, it, `(+ 1 2)`, time.Second); err != nil || !equal(ret, MakeInt(3)) {
+		t.Errorf("expected 3, got %v %v", ret, err)
+	}
+}
+
+func TestWithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data.txt": {Data: []byte("one\ntwo\n")},
+	}
+
+	it := New(WithFS(fsys))
+
+	if ret, err := evalContext(t, it, `(readfile "data.txt")`, time.Second); err != nil || !equal(ret, String{value: "one\ntwo\n"}) {
+		t.Errorf("expected the content of data.txt, got %v %v", ret, err)
+	}
+
+	if ret, err := evalContext(t, it, `(readlines "data.txt")`, time.Second); err != nil || !equal(ret, List{items: []any{String{value: "one"}, String{value: "two"}}}) {
+		t.Errorf("expected the lines of data.txt, got %v %v", ret, err)
+	}
+
+	// the host files are not accessible
+	if ret, err := evalContext(t, it, `(readfile "go.mod")`, time.Second); err == nil {
+		t.Errorf("expected an error reading go.mod, got %v", ret)
+	}
+
+	// MapFS can't write files
+	if ret, err := evalContext(t, it, `(writefile "new.txt" "x")`, time.Second); err == nil {
+		t.Errorf("expected an error writing new.txt, got %v", ret)
+	}
+
+	if _, ok := fsys["new.txt"]; ok {
+		t.Error("new.txt written")
+	}
+}
+
+func TestRandRange(t *testing.T) {
+	it := New()
+
+	for _, src := range []string{`(rand 0)`, `(rand -5)`} {
+		if ret, err := evalContext(t, it, src, time.Second); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("%v: expected %v, got %v %v", src, ErrOutOfRange, ret, err)
+		}
+	}
+
+	if ret, err := evalContext(t, it, `(rand 1)`, time.Second); err != nil || !equal(ret, MakeInt(0)) {
+		t.Errorf("expected 0, got %v %v", ret, err)
+	}
+}
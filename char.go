@@ -0,0 +1,215 @@
+package gisp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Char is the character type (a unicode code point), with the reader syntax #\a, #\space, #\newline
+type Char struct {
+	value rune
+}
+
+// charnames are the names of the characters that can't be written as #\c
+var charnames = map[string]rune{
+	"space":     ' ',
+	"newline":   '\n',
+	"tab":       '\t',
+	"return":    '\r',
+	"nul":       0,
+	"null":      0,
+	"escape":    0x1b,
+	"delete":    0x7f,
+	"backspace": '\b',
+	"alarm":     '\a',
+}
+
+// charname returns the name of a character in the #\name syntax (the character itself, if it's printable)
+func charname(r rune) string {
+	switch r {
+	case ' ':
+		return "space"
+	case '\n':
+		return "newline"
+	case '\t':
+		return "tab"
+	case '\r':
+		return "return"
+	case 0:
+		return "nul"
+	case 0x1b:
+		return "escape"
+	case 0x7f:
+		return "delete"
+	case '\b':
+		return "backspace"
+	case '\a':
+		return "alarm"
+	}
+
+	if !unicode.IsPrint(r) {
+		return fmt.Sprintf("x%x", r)
+	}
+
+	return string(r)
+}
+
+// MakeChar creates a Char object
+func MakeChar(r rune) Char {
+	return Char{value: r}
+}
+
+func (o Char) String() string { return string(o.value) }
+func (o Char) Value() any     { return o.value }
+func (o Char) Int() int64     { return int64(o.value) }
+func (o Char) Bool() bool     { return true }
+
+func (o Char) Eq(v any) bool {
+	c, ok := v.(Char)
+	return ok && o.value == c.value
+}
+
+func (o Char) Lt(v any) bool {
+	c, ok := v.(Char)
+	return ok && o.value < c.value
+}
+
+func (o Char) Leq(v any) bool {
+	c, ok := v.(Char)
+	return ok && o.value <= c.value
+}
+
+func (o Char) Gt(v any) bool {
+	c, ok := v.(Char)
+	return ok && o.value > c.value
+}
+
+func (o Char) Geq(v any) bool {
+	c, ok := v.(Char)
+	return ok && o.value >= c.value
+}
+
+// parseChar parses a character literal, after #\
+func (p *Parser) parseChar() (Char, error) {
+	ch := p.s.Next()
+	if ch == scanner.EOF {
+		return Char{}, ErrInvalid
+	}
+
+	name := string(ch) + p.scanToken()
+
+	if utf8.RuneCountInString(name) == 1 {
+		return Char{value: ch}, nil
+	}
+
+	if r, ok := charnames[strings.ToLower(name)]; ok {
+		return Char{value: r}, nil
+	}
+
+	if name[0] == 'x' { // #\x41
+		if r, err := strconv.ParseInt(name[1:], 16, 32); err == nil && r <= unicode.MaxRune {
+			return Char{value: rune(r)}, nil
+		}
+	}
+
+	return Char{}, fmt.Errorf("%w: #\\%v", ErrInvalid, name)
+}
+
+// getchar returns the char in the first argument
+func getchar(env *Env, args []any) rune {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	c, ok := env.Get(args[0]).(Char)
+	if !ok {
+		invalidType(args[0])
+	}
+
+	return c.value
+}
+
+// charpred returns a builtin that checks a property of a char
+func charpred(f func(rune) bool) Call {
+	return func(env *Env, args []any) any {
+		return Boolean{value: f(getchar(env, args))}
+	}
+}
+
+// charfunc returns a builtin that converts a char
+func charfunc(f func(rune) rune) Call {
+	return func(env *Env, args []any) any {
+		return Char{value: f(getchar(env, args))}
+	}
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// char->integer char
+		//
+		"char->integer": func(env *Env, args []any) any {
+			return MakeInt(int64(getchar(env, args)))
+		},
+
+		//
+		// integer->char integer
+		//
+		"integer->char": func(env *Env, args []any) any {
+			i := getint(env, args)
+			if i < 0 || i > unicode.MaxRune {
+				return Throw(MakeError(fmt.Errorf("%w: %v", ErrOutOfRange, i)))
+			}
+
+			return Char{value: rune(i)}
+		},
+
+		"char-upcase":      charfunc(unicode.ToUpper),
+		"char-downcase":    charfunc(unicode.ToLower),
+		"char-alphabetic?": charpred(unicode.IsLetter),
+		"char-numeric?":    charpred(unicode.IsDigit),
+		"char-whitespace?": charpred(unicode.IsSpace),
+
+		//
+		// string-ref string index (rune index)
+		//
+		"string-ref": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+			runes := []rune(s)
+			return Char{value: runes[getindex(env, args, len(runes))]}
+		},
+
+		//
+		// list->string list (of chars or strings)
+		//
+		"list->string": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			l, ok := env.Get(args[0]).(List)
+			if !ok {
+				return invalidType(args[0])
+			}
+
+			var sb strings.Builder
+
+			for _, v := range l.items {
+				switch t := v.(type) {
+				case Char:
+					sb.WriteRune(t.value)
+				case String:
+					sb.WriteString(t.value)
+				default:
+					return invalidType(v)
+				}
+			}
+
+			return String{value: sb.String()}
+		},
+	})
+}
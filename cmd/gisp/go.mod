@@ -1,6 +1,6 @@
 module github.com/raff/gisp/cmd/gisp
 
-go 1.20
+go 1.21
 
 require (
 	github.com/raff/gisp v1.0.0
@@ -80,7 +80,8 @@ func main() {
 	var p *gisp.Parser
 	var rl *readliner.ReadLiner
 
-	gisp.AddBuiltin("with-html", builtinHtml)
+	it := gisp.New(gisp.WithModules(gisp.MathModule))
+	it.AddBuiltin("with-html", builtinHtml)
 
 	if *expr {
 		p = gisp.NewParser(strings.NewReader(strings.Join(flag.Args(), " ")))
@@ -96,14 +97,15 @@ func main() {
 	} else if *interactive {
 		rl = readliner.New("> ", ".gisp_history")
 		rl.SetContPrompt(": ")
-		rl.SetCompletions(gisp.Builtins(), false)
+		rl.SetCompletions(it.Builtins(), false)
 		defer rl.Close()
 		p = gisp.NewParser(rl)
 	} else {
 		p = gisp.NewParser(os.Stdin)
 	}
 
-	env := gisp.NewEnv(nil)
+	env := it.Env()
+	p.SetEnv(env) // enable read-time evaluation (#.expr)
 
 	if *interactive {
 		for {
@@ -115,8 +117,14 @@ func main() {
 			}
 
 			for _, v := range l {
-				v = env.Get(v)
-				fmt.Println(gisp.Eval(env, v))
+				ret, err := gisp.EvalE(env, v)
+				if err != nil {
+					fmt.Println("error:", err)
+					continue
+				}
+
+				gisp.PrettyPrint(os.Stdout, ret, 0)
+				fmt.Println()
 			}
 		}
 
@@ -132,7 +140,11 @@ func main() {
 	var ret any
 
 	for _, v := range l {
-		ret = gisp.Eval(env, v)
+		ret, err = gisp.EvalE(env, v)
+		if err != nil {
+			fmt.Println("error:", err)
+			return
+		}
 	}
 
 	fmt.Println(ret)
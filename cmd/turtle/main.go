@@ -133,7 +133,9 @@ func callTurtle(env *gisp.Env, args []any) any {
 	turtle.Start(params, func(w turtle.Window) {
 		t := Turtle{win: w, turtle: w.NewTurtle()}
 		t.input = t.win.GetCanvas().SubscribeToJustPressedUserInput()
-		gisp.CallLambda(ldraw, env, []any{t})
+		if _, err := gisp.CallE(env, ldraw, t); err != nil {
+			fmt.Println("error:", err)
+		}
 	})
 
 	return gisp.Nil
@@ -723,35 +725,36 @@ func main() {
 		p = gisp.NewParser(os.Stdin)
 	}
 
-	gisp.AddBuiltin("color", callColor)
-	gisp.AddBuiltin("turtle", callTurtle)
-	gisp.AddBuiltin("exit", callExit)
-	gisp.AddBuiltin("clear", callClear)
-	gisp.AddBuiltin("show", callShow)
-	gisp.AddBuiltin("scale", callScale)
-	gisp.AddBuiltin("pendown", callPenDown)
-	gisp.AddBuiltin("penup", callPenUp)
-	gisp.AddBuiltin("speed", callSpeed)
-	gisp.AddBuiltin("pencolor", callPenColor)
-	gisp.AddBuiltin("fill", callFill)
-	gisp.AddBuiltin("size", callSize)
-	gisp.AddBuiltin("dot", callDot)
-	gisp.AddBuiltin("angle", callAngle)
-	gisp.AddBuiltin("left", callLeft)
-	gisp.AddBuiltin("right", callRight)
-	gisp.AddBuiltin("panleft", callPanLeft)
-	gisp.AddBuiltin("panright", callPanRight)
-	gisp.AddBuiltin("backward", callBackward)
-	gisp.AddBuiltin("forward", callForward)
-	gisp.AddBuiltin("goto", callGoTo)
-	gisp.AddBuiltin("pos", callPos)
-	gisp.AddBuiltin("pointto", callPointTo)
-	gisp.AddBuiltin("circle", callCircle)
-	gisp.AddBuiltin("pressed", callPressed)
-	gisp.AddBuiltin("justpressed", callJustPressed)
-	gisp.AddBuiltin("mousepos", callMousePos)
-
-	env := gisp.NewEnv(nil)
+	it := gisp.New(gisp.WithModules(gisp.MathModule))
+	it.AddBuiltin("color", callColor)
+	it.AddBuiltin("turtle", callTurtle)
+	it.AddBuiltin("exit", callExit)
+	it.AddBuiltin("clear", callClear)
+	it.AddBuiltin("show", callShow)
+	it.AddBuiltin("scale", callScale)
+	it.AddBuiltin("pendown", callPenDown)
+	it.AddBuiltin("penup", callPenUp)
+	it.AddBuiltin("speed", callSpeed)
+	it.AddBuiltin("pencolor", callPenColor)
+	it.AddBuiltin("fill", callFill)
+	it.AddBuiltin("size", callSize)
+	it.AddBuiltin("dot", callDot)
+	it.AddBuiltin("angle", callAngle)
+	it.AddBuiltin("left", callLeft)
+	it.AddBuiltin("right", callRight)
+	it.AddBuiltin("panleft", callPanLeft)
+	it.AddBuiltin("panright", callPanRight)
+	it.AddBuiltin("backward", callBackward)
+	it.AddBuiltin("forward", callForward)
+	it.AddBuiltin("goto", callGoTo)
+	it.AddBuiltin("pos", callPos)
+	it.AddBuiltin("pointto", callPointTo)
+	it.AddBuiltin("circle", callCircle)
+	it.AddBuiltin("pressed", callPressed)
+	it.AddBuiltin("justpressed", callJustPressed)
+	it.AddBuiltin("mousepos", callMousePos)
+
+	env := it.Env()
 
 	if *interactive {
 		for {
@@ -762,7 +765,13 @@ func main() {
 			}
 
 			for _, v := range l {
-				fmt.Println(gisp.Eval(env, v))
+				ret, err := gisp.EvalE(env, v)
+				if err != nil {
+					fmt.Println("error:", err)
+					continue
+				}
+
+				fmt.Println(ret)
 			}
 		}
 
@@ -778,7 +787,11 @@ func main() {
 	var ret any
 
 	for _, v := range l {
-		ret = gisp.Eval(env, v)
+		ret, err = gisp.EvalE(env, v)
+		if err != nil {
+			fmt.Println("error:", err)
+			return
+		}
 	}
 
 	fmt.Println(ret)
@@ -0,0 +1,379 @@
+package gisp
+
+import (
+	"fmt"
+)
+
+// jump is the panic payload used to transfer control out of loops (break, continue) and blocks (return-from).
+// It's not a thrown object, so it can't be caught by try.
+type jump struct {
+	kind  string // break, continue or return-from
+	block string // the target block, for return-from
+	value any
+}
+
+func (j jump) Error() string {
+	if j.kind == "return-from" {
+		return fmt.Sprintf("(return-from %v) outside of block %v", j.block, j.block)
+	}
+
+	return fmt.Sprintf("(%v) outside of a loop", j.kind)
+}
+
+// error returns the error for a jump outside of its target
+func (j jump) error() Error {
+	return MakeError(fmt.Errorf("%w: %v", ErrJump, j))
+}
+
+// exitLambda throws an error for break and continue, that can't exit a function.
+// It must be called with defer.
+func exitLambda() {
+	if r := recover(); r != nil {
+		if j, ok := r.(jump); ok && j.kind != "return-from" {
+			Throw(j.error())
+		}
+
+		panic(r)
+	}
+}
+
+// evalLoopBody evaluates the body of a loop once, returning true if the loop was interrupted by break
+func evalLoopBody(env *Env, body []any) (ret any, stop bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			j, ok := r.(jump)
+			if !ok || j.kind == "return-from" {
+				panic(r)
+			}
+
+			ret, stop = j.value, j.kind == "break"
+		}
+	}()
+
+	for _, v := range body {
+		if env.verbose() {
+			fmt.Println("  ", v)
+		}
+
+		ret = Eval(env, v)
+	}
+
+	return
+}
+
+// evalBlock evaluates the body of a named block, returning the value passed to return-from
+func evalBlock(env *Env, name string, body []any) (ret any) {
+	defer func() {
+		if r := recover(); r != nil {
+			j, ok := r.(jump)
+			if !ok || j.kind != "return-from" || j.block != name {
+				panic(r)
+			}
+
+			ret = j.value
+		}
+	}()
+
+	return resolve(evalBody(env, body))
+}
+
+// hasReturnFrom checks if a form contains (return-from name ...)
+func hasReturnFrom(v any, name string) bool {
+	l, ok := v.(List)
+	if !ok || len(l.items) == 0 {
+		return false
+	}
+
+	if s, ok := l.items[0].(Symbol); ok && s.value == "return-from" && len(l.items) > 1 {
+		if b, ok := l.items[1].(Symbol); ok && b.value == name {
+			return true
+		}
+	}
+
+	for _, item := range l.items {
+		if hasReturnFrom(item, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loopvar parses the loop specification (var expr...) of dotimes, dolist and for
+func loopvar(env *Env, args []any, min int) (Symbol, []any, []any) {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	spec, ok := args[0].(List)
+	if !ok || len(spec.items) < min+1 {
+		invalidType(args[0])
+	}
+
+	name, ok := spec.items[0].(Symbol)
+	if !ok {
+		invalidType(spec.items[0])
+	}
+
+	return name, spec.items[1:], args[1:]
+}
+
+// loop evaluates the body for each value returned by next, with the loop variable bound in a new environment.
+// It returns the value passed to break, or the result expression, evaluated with the loop variable
+// bound to the last value returned by next.
+func loop(env *Env, name Symbol, next func() (any, bool), body []any, result []any) any {
+	for {
+		env.check()
+
+		v, ok := next()
+
+		lenv := NewEnv(env)
+		lenv.PutLocal(name, v)
+
+		if !ok {
+			if len(result) > 0 {
+				return lenv.Get(result[0])
+			}
+
+			return Nil
+		}
+
+		if ret, stop := evalLoopBody(lenv, body); stop {
+			return ret
+		}
+	}
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// cond (test expr...)... [(else expr...)] | (test => function)
+		//
+		"cond": func(env *Env, args []any) any {
+			for _, c := range args {
+				clause, ok := c.(List)
+				if !ok || len(clause.items) == 0 {
+					return invalidType(c)
+				}
+
+				test, body := clause.items[0], clause.items[1:]
+
+				var v any = True
+
+				if s, ok := test.(Symbol); !ok || s.value != "else" {
+					v = env.Get(test)
+				}
+
+				if !AsBool(v, false) {
+					continue
+				}
+
+				if len(body) == 0 {
+					return v
+				}
+
+				if s, ok := body[0].(Symbol); ok && s.value == "=>" {
+					if len(body) < 2 {
+						return ErrMissing
+					}
+
+					return apply(env, env.Get(body[1]), []any{v})
+				}
+
+				return evalBody(env, body)
+			}
+
+			return Nil
+		},
+
+		//
+		// case key ((datum...) expr...)... [(else expr...)]
+		//
+		"case": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			key := env.Get(args[0])
+
+			for _, c := range args[1:] {
+				clause, ok := c.(List)
+				if !ok || len(clause.items) == 0 {
+					return invalidType(c)
+				}
+
+				match := false
+
+				switch t := clause.items[0].(type) {
+				case List:
+					for _, d := range t.items {
+						if equal(key, d) {
+							match = true
+							break
+						}
+					}
+
+				case Symbol:
+					match = t.value == "else" || equal(key, t)
+
+				default:
+					match = equal(key, t)
+				}
+
+				if match {
+					return evalBody(env, clause.items[1:])
+				}
+			}
+
+			return Nil
+		},
+
+		//
+		// when cond stmt...
+		//
+		"when": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			if AsBool(env.Get(args[0]), false) {
+				return evalBody(env, args[1:])
+			}
+
+			return Nil
+		},
+
+		//
+		// unless cond stmt...
+		//
+		"unless": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			if !AsBool(env.Get(args[0]), false) {
+				return evalBody(env, args[1:])
+			}
+
+			return Nil
+		},
+
+		//
+		// dotimes (var count [result]) stmt...
+		//
+		"dotimes": func(env *Env, args []any) any {
+			name, spec, body := loopvar(env, args, 1)
+
+			n, i := getint(env, spec), 0
+
+			return loop(env, name, func() (any, bool) {
+				if i >= n {
+					return MakeInt(n), false
+				}
+
+				i++
+				return MakeInt(i - 1), true
+			}, body, spec[1:])
+		},
+
+		//
+		// dolist (var list [result]) stmt...
+		//
+		"dolist": func(env *Env, args []any) any {
+			name, spec, body := loopvar(env, args, 1)
+
+			l, result := getseq(env, spec)
+			i := 0
+
+			return loop(env, name, func() (any, bool) {
+				if i >= len(l) {
+					return Nil, false
+				}
+
+				i++
+				return l[i-1], true
+			}, body, result)
+		},
+
+		//
+		// for (var start end [step]) stmt... (from start to end, excluded)
+		//
+		"for": func(env *Env, args []any) any {
+			name, spec, body := loopvar(env, args, 2)
+
+			i, end, step := getint(env, spec), getint(env, spec[1:]), 1
+			if len(spec) > 2 {
+				step = getint(env, spec[2:])
+			}
+
+			if step == 0 {
+				return Throw(MakeError(fmt.Errorf("%w: step 0", ErrOutOfRange)))
+			}
+
+			return loop(env, name, func() (any, bool) {
+				if (step > 0 && i >= end) || (step < 0 && i <= end) {
+					return MakeInt(i), false
+				}
+
+				i += step
+				return MakeInt(i - step), true
+			}, body, nil)
+		},
+
+		//
+		// break [value] (exits the innermost loop)
+		//
+		"break": func(env *Env, args []any) any {
+			var v any = Nil
+			if len(args) > 0 {
+				v = env.Get(args[0])
+			}
+
+			panic(jump{kind: "break", value: v})
+		},
+
+		//
+		// continue (skips to the next iteration of the innermost loop)
+		//
+		"continue": func(env *Env, args []any) any {
+			panic(jump{kind: "continue", value: Nil})
+		},
+
+		//
+		// block name stmt...
+		//
+		"block": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			name, ok := args[0].(Symbol)
+			if !ok {
+				return invalidType(args[0])
+			}
+
+			return evalBlock(env, name.value, args[1:])
+		},
+
+		//
+		// return-from name [value] (exits the block, or the function defined with defun, with the same name)
+		//
+		"return-from": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			name, ok := args[0].(Symbol)
+			if !ok {
+				return invalidType(args[0])
+			}
+
+			var v any = Nil
+			if len(args) > 1 {
+				v = env.Get(args[1])
+			}
+
+			panic(jump{kind: "return-from", block: name.value, value: v})
+		},
+	})
+}
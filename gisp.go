@@ -2,16 +2,22 @@ package gisp
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
+	"math"
 	"math/rand"
-	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"text/scanner"
 	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -19,6 +25,13 @@ var (
 	ErrInvalid     = Error{value: fmt.Errorf("invalid-token")}
 	ErrInvalidType = Error{value: fmt.Errorf("invalid-parameter-type")}
 	ErrMissing     = Error{value: fmt.Errorf("missing-parameter")}
+	ErrDivByZero   = Error{value: fmt.Errorf("division-by-zero")}
+	ErrStepLimit   = Error{value: fmt.Errorf("step-limit-exceeded")}
+	ErrDepthLimit  = Error{value: fmt.Errorf("call-depth-exceeded")}
+	ErrPermission  = Error{value: fmt.Errorf("permission-denied")}
+	ErrOutOfRange  = Error{value: fmt.Errorf("index-out-of-range")}
+	ErrArity       = Error{value: fmt.Errorf("wrong-number-of-arguments")}
+	ErrJump        = Error{value: fmt.Errorf("invalid-jump")}
 	Verbose        = false
 
 	True = Boolean{value: true}
@@ -34,26 +47,44 @@ var (
 		true:  1,
 	}
 
-	builtins map[string]Call
+	builtins map[string]Call // core builtins, copied in each new Interpreter
+
+	defaultInterpreter *Interpreter
+	defaultOnce        sync.Once
 )
 
-// Call is the signature for builtin methods
+// Call is the signature for builtin methods.
+// A builtin can report a failure returning ErrMissing or ErrInvalidType, or calling Throw.
 type Call func(env *Env, args []any) any
 
-// AddBuiltin adds a new built-in method.
+// Default returns the default interpreter, used by the package level functions
+func Default() *Interpreter {
+	defaultOnce.Do(func() {
+		defaultInterpreter = New()
+	})
+
+	return defaultInterpreter
+}
+
+// AddBuiltin adds a new built-in method to the default interpreter.
 // Note that it can override existing builtin methods.
 func AddBuiltin(name string, value Call) {
-	builtins[name] = value
+	Default().AddBuiltin(name, value)
 }
 
-// Builtins returns a list of builtin method (names)
+// Builtins returns a list of builtin method (names) of the default interpreter.
 // Note that this doesn't return the math and conditional operators.
-func Builtins() (l []string) {
-	for k, _ := range builtins {
-		l = append(l, k)
+func Builtins() []string {
+	return Default().Builtins()
+}
+
+// addBuiltins adds a set of builtins to the core builtins (it should be called by init functions)
+func addBuiltins(m map[string]Call) {
+	if builtins == nil {
+		builtins = map[string]Call{}
 	}
 
-	return
+	maps.Copy(builtins, m)
 }
 
 // Object is the interface for all gisp objects.
@@ -94,6 +125,7 @@ type Error struct {
 func (o Error) String() string { return o.value.Error() }
 func (o Error) Value() any     { return o.value }
 func (o Error) Error() string  { return o.value.Error() }
+func (o Error) Unwrap() error  { return o.value }
 
 // Boolean is the boolean primitive object
 type Boolean struct {
@@ -147,25 +179,53 @@ func (o Boolean) Geq(v any) bool {
 // Symbol is the symbol atom
 type Symbol struct {
 	value string
+	pos   *scanner.Position
 }
 
 func (o Symbol) String() string { return o.value }
 func (o Symbol) Value() any     { return o.value }
 
+// Pos returns the source position of the symbol, if it was created by the Parser
+func (o Symbol) Pos() scanner.Position { return position(o.pos) }
+
 // Quoted is for quoted symbols
 type Quoted struct {
 	value any
 }
 
-func (o Quoted) String() string { return fmt.Sprintf("'%v", o.value) }
+func (o Quoted) String() string { return DisplayString(o) }
 func (o Quoted) Value() any     { return o.value }
 
+// Quasiquoted is for quasi-quoted templates (`form)
+type Quasiquoted struct {
+	value any
+}
+
+func (o Quasiquoted) String() string { return DisplayString(o) }
+func (o Quasiquoted) Value() any     { return o.value }
+
+// Unquoted is for expressions evaluated inside a quasi-quoted template (,form)
+type Unquoted struct {
+	value any
+}
+
+func (o Unquoted) String() string { return DisplayString(o) }
+func (o Unquoted) Value() any     { return o.value }
+
+// Spliced is for list expressions spliced inside a quasi-quoted template (,@form)
+type Spliced struct {
+	value any
+}
+
+func (o Spliced) String() string { return DisplayString(o) }
+func (o Spliced) Value() any     { return o.value }
+
 // Op is for math operators ( +, -, *, / )
 type Op struct {
 	value string
 }
 
-func (o Op) String() string { return fmt.Sprintf("%q", o.value) }
+func (o Op) String() string { return o.value }
 func (o Op) Value() any     { return o.value }
 
 // Cond is for conditional operators ( =, <, <=, >, >= )
@@ -173,7 +233,7 @@ type Cond struct {
 	value string
 }
 
-func (o Cond) String() string { return fmt.Sprintf("%q", o.value) }
+func (o Cond) String() string { return o.value }
 func (o Cond) Value() any     { return o.value }
 
 // Integer is the integer primitive type (int64)
@@ -188,43 +248,28 @@ func (o Integer) Float() float64 { return float64(o.value) }
 func (o Integer) Bool() bool     { return true }
 
 func (o Integer) Eq(v any) bool {
-	if i, ok := v.(CanInt); ok {
-		return o.value == i.Int()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c == 0
 }
 
 func (o Integer) Lt(v any) bool {
-	if i, ok := v.(CanInt); ok {
-		return o.value < i.Int()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c < 0
 }
 
 func (o Integer) Leq(v any) bool {
-	if i, ok := v.(CanInt); ok {
-		return o.value <= i.Int()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c <= 0
 }
 
 func (o Integer) Gt(v any) bool {
-	if i, ok := v.(CanInt); ok {
-		return o.value > i.Int()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c > 0
 }
 
 func (o Integer) Geq(v any) bool {
-	if i, ok := v.(CanInt); ok {
-		return o.value >= i.Int()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c >= 0
 }
 
 // Float is the floating point primitive type (float64)
@@ -232,50 +277,35 @@ type Float struct {
 	value float64
 }
 
-func (o Float) String() string { return fmt.Sprint(o.value) }
+func (o Float) String() string { return formatFloat(o.value) }
 func (o Float) Value() any     { return o.value }
 func (o Float) Int() int64     { return int64(o.value) }
 func (o Float) Float() float64 { return o.value }
 func (o Float) Bool() bool     { return true }
 
 func (o Float) Eq(v any) bool {
-	if f, ok := v.(CanFloat); ok {
-		return o.value == f.Float()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c == 0
 }
 
 func (o Float) Lt(v any) bool {
-	if f, ok := v.(CanFloat); ok {
-		return o.value < f.Float()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c < 0
 }
 
 func (o Float) Leq(v any) bool {
-	if f, ok := v.(CanFloat); ok {
-		return o.value <= f.Float()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c <= 0
 }
 
 func (o Float) Gt(v any) bool {
-	if f, ok := v.(CanFloat); ok {
-		return o.value > f.Float()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c > 0
 }
 
 func (o Float) Geq(v any) bool {
-	if f, ok := v.(CanFloat); ok {
-		return o.value >= f.Float()
-	}
-
-	return false
+	c, ok := numcmp(o, v)
+	return ok && c >= 0
 }
 
 // String is the string primitive type
@@ -330,16 +360,14 @@ func (o String) Geq(v any) bool {
 // List is the list type
 type List struct {
 	items []any
+	pos   *scanner.Position
 }
 
-func (o List) String() string {
-	l := fmt.Sprint(o.items)
-	if l[0] == '[' {
-		return "(" + l[1:len(l)-1] + ")"
-	}
-	return l
-}
-func (o List) Value() any { return o.items }
+func (o List) String() string { return DisplayString(o) }
+func (o List) Value() any     { return o.items }
+
+// Pos returns the source position of the list, if it was created by the Parser
+func (o List) Pos() scanner.Position { return position(o.pos) }
 
 func (o List) Item(i int) any {
 	if i < 0 || i > len(o.items) {
@@ -357,14 +385,46 @@ func (o List) Bool() bool {
 	return len(o.items) > 0
 }
 
-// Lambda is the anonymous function type
+// Builtin is a builtin method, as a first-class object (the value of a builtin name that is not shadowed by a variable)
+type Builtin struct {
+	name string
+	call Call
+}
+
+func (o Builtin) String() string { return fmt.Sprintf("#<builtin %v>", o.name) }
+func (o Builtin) Value() any     { return o.call }
+func (o Builtin) Bool() bool     { return true }
+
+// Name returns the name of the builtin
+func (o Builtin) Name() string { return o.name }
+
+// Call calls the builtin with the input (unevaluated) arguments
+func (o Builtin) Call(env *Env, args []any) any {
+	return o.call(env, args)
+}
+
+// Lambda is the anonymous function type.
+// It captures the environment where it was defined (lexical closure).
 type Lambda struct {
-	args []any
-	body []any
+	name   string // set for functions created with defun or define
+	doc    string
+	args   []any
+	params *params
+	body   []any
+	env    *Env
+	block  bool // the body is evaluated in a block with the name of the function (see return-from)
 }
 
-func (o Lambda) String() string { return fmt.Sprintf("(lambda %v %v)", o.args, o.body) }
-func (o Lambda) Value() any     { return Nil }
+// String returns the source of the function: (defun name (args) body...) or (lambda (args) body...)
+func (o Lambda) String() string { return DisplayString(o) }
+
+func (o Lambda) Value() any { return Nil }
+
+// Name returns the name of the function (empty for anonymous lambdas)
+func (o Lambda) Name() string { return o.name }
+
+// Doc returns the documentation string of the function
+func (o Lambda) Doc() string { return o.doc }
 
 func (o Lambda) Arg(i int) any {
 	if i < 0 || i >= len(o.args) {
@@ -374,21 +434,62 @@ func (o Lambda) Arg(i int) any {
 	return o.args[i]
 }
 
-func ident(v string) Object {
+// Macro is the user defined macro type.
+// The arguments are passed unevaluated and the returned form is evaluated in the caller environment.
+type Macro struct {
+	name   string
+	args   []any
+	params *params
+	body   []any
+	env    *Env
+}
+
+func (o Macro) String() string { return DisplayString(o) }
+func (o Macro) Value() any     { return Nil }
+
+// expand binds the (unevaluated) input arguments and evaluates the macro body,
+// returning the expanded form.
+func (o Macro) expand(env *Env, args []any) (ret any) {
+	menv := NewEnv(o.env)
+	menv.run = env.run
+	o.params.bind(menv, o.name, args)
+
+	for _, v := range o.body {
+		if menv.verbose() {
+			fmt.Println("  ", v)
+		}
+		ret = Eval(menv, v)
+	}
+
+	return
+}
+
+func position(pos *scanner.Position) scanner.Position {
+	if pos == nil {
+		return scanner.Position{}
+	}
+
+	return *pos
+}
+
+func ident(v string, pos scanner.Position) Object {
 	switch v {
 	case "true":
 		return True
 
 	case "nil":
 		return Nil
+
+	case "nan":
+		return Float{value: math.NaN()}
 	}
 
-	return Symbol{value: v}
+	return Symbol{value: v, pos: &pos}
 }
 
 func quote(v any) any {
 	switch v.(type) {
-	case Symbol, List:
+	case Symbol, List, Map, Vector:
 		if Verbose {
 			fmt.Println("Quote", v)
 		}
@@ -398,52 +499,156 @@ func quote(v any) any {
 	return v
 }
 
+// ParseError is the error returned by the Parser for invalid input
+type ParseError struct {
+	Pos scanner.Position
+	Err error
+}
+
+func (e ParseError) Error() string { return fmt.Sprintf("%v: %v", e.Pos, e.Err) }
+func (e ParseError) Unwrap() error { return e.Err }
+
 // Parser can parse a gisp object or program
 type Parser struct {
-	s scanner.Scanner
+	s   scanner.Scanner
+	err error // the first error reported by the scanner
+
+	macros map[rune]ReaderMacro // reader macros, by dispatch character
+	tags   map[string]TagFunc   // tagged literal constructors
+	env    *Env                 // environment for read-time evaluation (disabled if nil)
 }
 
-// NewParser creates a new Parser object that can parse the input Reader
+// NewParser creates a new Parser object that can parse the input Reader.
+// If the Reader has a Name (i.e. it's an os.File) it is used as filename for the source positions.
 func NewParser(r io.Reader) *Parser {
-	var p Parser
+	p := Parser{macros: maps.Clone(readerMacros), tags: maps.Clone(readerTags)}
 
 	p.s.Init(r)
+	if f, ok := r.(interface{ Name() string }); ok {
+		p.s.Filename = f.Name()
+	}
 	p.s.Whitespace = 0
-	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings | scanner.ScanRawStrings
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings
 	p.s.IsIdentRune = func(ch rune, i int) bool {
-		return ch == '_' || ch == '$' || ch == ':' || unicode.IsLetter(ch) || unicode.IsDigit(ch) && i > 0
+		return ch == '_' || ch == '$' || ch == ':' || ch == '&' || unicode.IsLetter(ch) || unicode.IsDigit(ch) && i > 0
+	}
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		if p.err == nil {
+			p.err = ParseError{Pos: s.Pos(), Err: fmt.Errorf("%w: %v", ErrInvalid, msg)}
+		}
 	}
 	return &p
 }
 
+// skipLine skips a line comment, up to the end of the line (the newline is not consumed)
+func (p *Parser) skipLine() {
+	for ch := p.s.Peek(); ch != '\n' && ch != scanner.EOF; ch = p.s.Peek() {
+		p.s.Next()
+	}
+}
+
+// skipBlock skips a (nested) block comment #| ... |#, after the opening #|
+func (p *Parser) skipBlock() error {
+	depth := 1
+
+	for depth > 0 {
+		switch p.s.Next() {
+		case scanner.EOF:
+			return fmt.Errorf("%w: unterminated block comment", ErrInvalid)
+
+		case '|':
+			if p.s.Peek() == '#' {
+				p.s.Next()
+				depth--
+			}
+
+		case '#':
+			if p.s.Peek() == '|' {
+				p.s.Next()
+				depth++
+			}
+		}
+	}
+
+	return nil
+}
+
+// scanIdent returns the identifier in the current token, joined with the following characters up to the next separator
+// (they are not scanned as tokens, that could be invalid literals, i.e. 08 in a-08)
+func (p *Parser) scanIdent() string {
+	return p.s.TokenText() + p.scanToken()
+}
+
+// scanToken returns the characters up to the next separator
+func (p *Parser) scanToken() string {
+	var sb strings.Builder
+
+	for !p.SepNext() {
+		sb.WriteRune(p.s.Next())
+	}
+
+	return sb.String()
+}
+
+// scanNumber returns the number literal starting with st (the current token, with its sign), up to the next separator,
+// or an error if the literal is not a valid number
+func (p *Parser) scanNumber(st string) (any, error) {
+	pos := p.s.Position
+	p.err = nil // the literal is validated by parseNumber (the scanner rejects decimals like 08)
+
+	st += p.scanToken() // rationals, or invalid literals like 12abc
+
+	if err := p.err; err != nil {
+		p.err = nil
+		return nil, err
+	}
+
+	n, ok := parseNumber(st, 10)
+	if !ok {
+		return nil, ParseError{Pos: pos, Err: fmt.Errorf("%w: invalid number %v", ErrInvalid, st)}
+	}
+
+	return n, nil
+}
+
 // SepNext checks if the next character to parse is a separator between gisp objects
 func (p *Parser) SepNext() bool {
 	switch p.s.Peek() {
-	case ' ', '\t', '\r', '\n', '(', ')', scanner.EOF:
+	case ' ', '\t', '\r', '\n', '(', ')', '{', '}', '[', ']', ';', scanner.EOF:
 		return true
 	}
 
 	return false
 }
 
+// parseMode selects when parse stops
+type parseMode int
+
+const (
+	parseAll   parseMode = iota // until the end delimiter (or EOF)
+	parseLine                   // until the next white space (ParseOne)
+	parseDatum                  // after one object (Read)
+)
+
 // Parse parses the input from the Reader until EOF and returns a list of objects
 func (p *Parser) Parse() (l []any, err error) {
-	return p.parse(false)
+	return p.parse(parseAll, 0)
 }
 
 // ParseOne parses one object from the input
 func (p *Parser) ParseOne() (l []any, err error) {
-	return p.parse(true)
+	return p.parse(parseLine, 0)
 }
 
-func (p *Parser) parse(one bool) (l []any, err error) {
-	var neg bool
-	var quoted bool
+// parse parses a list of objects, until the end delimiter (or EOF)
+func (p *Parser) parse(mode parseMode, end rune) (l []any, err error) {
+	var sign string            // sign of the next number
+	var quoted []func(any) any // pending quote, quasiquote, unquote...
 
 	maybequoted := func(v any) any {
-		if quoted {
-			quoted = false
-			v = quote(v)
+		for len(quoted) > 0 {
+			last := len(quoted) - 1
+			v, quoted = quoted[last](v), quoted[:last]
 		}
 
 		return v
@@ -457,24 +662,62 @@ func (p *Parser) parse(one bool) (l []any, err error) {
 		return nil, ErrEOF
 	}
 
-	for tok := p.s.Scan(); tok != scanner.EOF; tok = p.s.Scan() {
+	for mode != parseDatum || len(l) == 0 {
+		tok := p.s.Scan()
+		if tok == scanner.EOF {
+			break
+		}
+
 		st := p.s.TokenText()
 
+		if err := p.err; err != nil && tok != scanner.Int && tok != scanner.Float {
+			p.err = nil
+			return nil, err
+		}
+
 		if Verbose {
 			fmt.Printf("%v: %v %q\n", p.s.Position, scanner.TokenString(tok), st)
 		}
 
 		switch tok {
 		case '(':
-			vv, err := p.parse(false)
+			pos := p.s.Position
+
+			vv, err := p.parse(parseAll, ')')
+			if err != nil {
+				return nil, err
+			}
+
+			appendtolist(List{items: vv, pos: &pos})
+
+		case '{':
+			pos := p.s.Position
+
+			vv, err := p.parse(parseAll, '}')
+			if err != nil {
+				return nil, err
+			}
+
+			if len(vv)%2 != 0 {
+				return nil, ParseError{Pos: pos, Err: ErrInvalid}
+			}
+
+			appendtolist(MakeMap(vv...))
+
+		case '[':
+			vv, err := p.parse(parseAll, ']')
 			if err != nil {
 				return nil, err
 			}
 
-			appendtolist(List{items: vv})
+			appendtolist(MakeVector(vv...))
+
+		case ')', '}', ']':
+			if (end != 0 && tok != end) || (end == 0 && mode == parseDatum) {
+				return nil, ParseError{Pos: p.s.Position, Err: ErrInvalid}
+			}
 
-		case ')':
-			if quoted {
+			if len(quoted) > 0 {
 				appendtolist(Nil)
 			}
 
@@ -484,57 +727,95 @@ func (p *Parser) parse(one bool) (l []any, err error) {
 			if Verbose {
 				fmt.Printf("separator: %d", tok)
 			}
-			if one {
+			if mode == parseLine {
 				return
 			}
 			continue
 
 		case scanner.Ident:
-			var id string
+			pos := p.s.Position
+			appendtolist(ident(p.scanIdent(), pos))
 
-			for {
-				id += st
+		case scanner.String:
+			st, _ = strconv.Unquote(st)
+			appendtolist(String{value: st})
 
-				if p.SepNext() {
-					break
-				}
+		case ';': // line comment
+			p.skipLine()
+
+		case '#':
+			v, err := p.dispatch()
+			if err != nil {
+				return nil, err
+			}
 
-				tok = p.s.Scan()
-				st = p.s.TokenText()
+			if v != nil { // comments don't return an object
+				appendtolist(v)
 			}
 
-			appendtolist(ident(id))
+		case '`':
+			if p.s.Peek() == '(' { // quasi-quoted list
+				quoted = append(quoted, func(v any) any { return Quasiquoted{value: v} })
+				continue
+			}
 
-		case scanner.String, scanner.RawString:
-			st, _ = strconv.Unquote(st)
-			appendtolist(String{value: st})
+			// raw string
+			var sb strings.Builder
 
-		case scanner.Int:
-			i, _ := strconv.ParseInt(st, 10, 64)
-			if neg {
-				i = -i
-				neg = false
+			for ch := p.s.Next(); ch != '`'; ch = p.s.Next() {
+				if ch == scanner.EOF {
+					return nil, ParseError{Pos: p.s.Position, Err: ErrInvalid}
+				}
+
+				sb.WriteRune(ch)
+			}
+
+			appendtolist(String{value: sb.String()})
+
+		case ',':
+			if p.s.Peek() == '@' {
+				p.s.Next()
+				quoted = append(quoted, func(v any) any { return Spliced{value: v} })
+			} else {
+				quoted = append(quoted, func(v any) any { return Unquoted{value: v} })
 			}
-			appendtolist(Integer{value: i})
 
-		case scanner.Float:
-			f, _ := strconv.ParseFloat(st, 64)
-			if neg {
-				f = -f
-				neg = false
+		case scanner.Int, scanner.Float:
+			n, err := p.scanNumber(sign + st)
+			if err != nil {
+				return nil, err
 			}
-			appendtolist(Float{value: f})
+
+			sign = ""
+			appendtolist(n)
 
 		case '\'':
 			if Verbose {
 				fmt.Println("quote")
 			}
-			quoted = true
+			quoted = append(quoted, quote)
 
 		case '+', '-', '/', '*', '%':
 			if tok == '+' || tok == '-' {
-				if n := p.s.Peek(); n == '.' || (n >= '0' && n <= '9') { // next token is a number
-					neg = tok == '-'
+				switch n := p.s.Peek(); {
+				case n == '.' || (n >= '0' && n <= '9'): // next token is a number
+					sign = st
+					continue
+
+				case n == 'i' || n == 'n': // +inf, -inf, +nan, -nan or an operator followed by a symbol
+					p.s.Scan()
+
+					pos := p.s.Position
+					id := p.scanIdent()
+
+					if id == "inf" || id == "nan" {
+						n, _ := parseNumber(st+id, 10)
+						appendtolist(n)
+					} else {
+						appendtolist(Op{value: st})
+						appendtolist(ident(id, pos))
+					}
+
 					continue
 				}
 			}
@@ -558,38 +839,188 @@ func (p *Parser) parse(one bool) (l []any, err error) {
 			}
 
 		case '=':
-			appendtolist(Cond{value: "="})
+			if p.s.Peek() == '>' { // => in cond clauses
+				p.s.Next()
+				appendtolist(ident("=>", p.s.Position))
+			} else {
+				appendtolist(Cond{value: "="})
+			}
 
 		default:
 			if Verbose {
 				fmt.Printf("UNKNOWN %v %q", scanner.TokenString(tok), st)
 			}
-			return nil, ErrInvalid
+			return nil, ParseError{Pos: p.s.Position, Err: ErrInvalid}
 		}
 	}
 
+	if end != 0 { // EOF before the end delimiter
+		return nil, ParseError{Pos: p.s.Pos(), Err: fmt.Errorf("%w: missing %c", ErrInvalid, end)}
+	}
+
 	return
 }
 
-func invalidType(v any) error {
+func invalidType(v any) any {
 	if Verbose {
 		fmt.Printf("invalid (%T) %#v", v, v)
 	}
 
-	return ErrInvalidType
+	return Throw(ErrInvalidType)
+}
+
+// Frame is an entry in the gisp stack trace: the name of the called function and the call site.
+type Frame struct {
+	Name string
+	Pos  scanner.Position
+}
+
+func (f Frame) String() string {
+	if f.Pos.IsValid() {
+		return fmt.Sprintf("%v (%v)", f.Name, f.Pos)
+	}
+
+	return f.Name
+}
+
+// EvalError is the error returned by EvalE for uncaught errors, with the gisp stack trace
+type EvalError struct {
+	Err   error
+	Trace []Frame
+}
+
+func (e EvalError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Err.Error())
+
+	for i := 0; i < len(e.Trace); {
+		f := e.Trace[i]
+		sb.WriteString("\n\tat " + f.String())
+
+		// collapse the repeated frames (i.e. recursive calls)
+		n := 1
+		for i+n < len(e.Trace) && e.Trace[i+n] == f {
+			n++
+		}
+		if n > 2 {
+			fmt.Fprintf(&sb, "\n\t... repeated %d more times", n-1)
+		} else {
+			n = 1
+		}
+
+		i += n
+	}
+
+	return sb.String()
+}
+
+func (e EvalError) Unwrap() error { return e.Err }
+
+// thrown is the panic value used to unwind the evaluation when an object is thrown
+type thrown struct {
+	value any
+	trace []Frame
+}
+
+func (t thrown) error() error {
+	err, ok := t.value.(error)
+	if !ok {
+		err = MakeError(fmt.Errorf("%v", t.value))
+	}
+
+	if len(t.trace) == 0 {
+		return err
+	}
+
+	return EvalError{Err: err, Trace: t.trace}
+}
+
+// fatal returns true for the errors that can't be caught (limits and canceled evaluation)
+func (t thrown) fatal() bool {
+	err, ok := t.value.(error)
+	if !ok {
+		return false
+	}
+
+	return errors.Is(err, ErrStepLimit) || errors.Is(err, ErrDepthLimit) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// callname returns the name of the function called by a list, for the stack trace
+func callname(l List) string {
+	switch t := l.items[0].(type) {
+	case Symbol:
+		return t.value
+
+	case Op:
+		return t.value
+
+	case Cond:
+		return t.value
+	}
+
+	return "lambda"
+}
+
+// Throw raises the input object (usually an Error), unwinding the evaluation
+// up to the closest try/catch (or EvalE).
+// It never returns: the return value is only there so that builtins can `return Throw(err)`.
+func Throw(v any) any {
+	if e, ok := v.(Error); ok {
+		// wrap the error, so that it can be passed around as a value once caught
+		v = Error{value: fmt.Errorf("%w", e)}
+	}
+
+	panic(thrown{value: v})
+}
+
+// failed returns true if the value returned by a builtin is one of the failure errors
+func failed(v any) bool {
+	if e, ok := v.(Error); ok {
+		return e == ErrMissing || e == ErrInvalidType
+	}
+
+	return false
+}
+
+// evalTry evaluates body, calling the catch clause, if present, when an object is thrown.
+func evalTry(env *Env, body []any, catch *List) (ret any) {
+	if catch != nil {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			t, ok := r.(thrown)
+			if !ok || t.fatal() {
+				panic(r)
+			}
+
+			cenv := NewEnv(env)
+			ret = nil
+
+			if len(catch.items) > 1 {
+				cenv.PutLocal(catch.items[1], t.value)
+				ret = resolve(evalBody(cenv, catch.items[2:]))
+			}
+		}()
+	}
+
+	return resolve(evalBody(env, body))
 }
 
 func init() {
 	// primitive functions
 
-	builtins = map[string]Call{
+	addBuiltins(map[string]Call{
 		//
 		// print args
 		//
 		"print": func(env *Env, args []any) any {
 			args = env.GetList(args)
 
-			fmt.Print(args...)
+			fmt.Fprint(env.interp.stdout, args...)
 
 			if len(args) > 0 {
 				return args[len(args)-1]
@@ -604,7 +1035,7 @@ func init() {
 		"println": func(env *Env, args []any) any {
 			args = env.GetList(args)
 
-			fmt.Println(args...)
+			fmt.Fprintln(env.interp.stdout, args...)
 
 			if len(args) > 0 {
 				return args[len(args)-1]
@@ -628,14 +1059,14 @@ func init() {
 				return invalidType(f)
 			}
 
-			return fmt.Sprintf(sfmt.String(), args...)
+			return String{value: fmt.Sprintf(sfmt.String(), args...)}
 		},
 
 		//
 		// readfile [filename]
 		//
 		"readfile": func(env *Env, args []any) any {
-			fin := os.Stdin
+			fin := env.interp.stdin
 
 			if len(args) > 0 {
 				fname, ok := env.Get(args[0]).(String)
@@ -643,9 +1074,9 @@ func init() {
 					return invalidType(args[0])
 				}
 
-				f, err := os.Open(fname.value)
+				f, err := env.interp.fsys.Open(fname.value)
 				if err != nil {
-					return MakeError(err)
+					return Throw(MakeError(err))
 				}
 
 				defer f.Close()
@@ -654,7 +1085,7 @@ func init() {
 
 			content, err := io.ReadAll(fin)
 			if err != nil {
-				return MakeError(err)
+				return Throw(MakeError(err))
 			}
 
 			return String{value: string(content)}
@@ -664,7 +1095,7 @@ func init() {
 		// readlines [filename]
 		//
 		"readlines": func(env *Env, args []any) any {
-			fin := os.Stdin
+			fin := env.interp.stdin
 
 			if len(args) > 0 {
 				fname, ok := env.Get(args[0]).(String)
@@ -672,9 +1103,9 @@ func init() {
 					return invalidType(args[0])
 				}
 
-				f, err := os.Open(fname.value)
+				f, err := env.interp.fsys.Open(fname.value)
 				if err != nil {
-					return MakeError(err)
+					return Throw(MakeError(err))
 				}
 
 				defer f.Close()
@@ -688,29 +1119,59 @@ func init() {
 				lines = append(lines, String{value: scanner.Text()})
 			}
 			if err := scanner.Err(); err != nil {
-				return MakeError(err)
+				return Throw(MakeError(err))
 			}
 
 			return List{items: lines}
 		},
 
 		//
-		// sleep ms
+		// writefile filename content
 		//
-		"sleep": func(env *Env, args []any) any {
-			if len(args) == 0 {
-
+		"writefile": func(env *Env, args []any) any {
+			if len(args) != 2 {
 				return ErrMissing
 			}
 
-			v := env.Get(args[0])
+			fname, ok := env.Get(args[0]).(String)
+			if !ok {
+				return invalidType(args[0])
+			}
 
-			if tm, ok := v.(CanInt); ok {
-				time.Sleep(time.Millisecond * time.Duration(tm.Int()))
-				return tm
+			content, ok := env.Get(args[1]).(String)
+			if !ok {
+				return invalidType(args[1])
 			}
 
-			return invalidType(v)
+			wfs, ok := env.interp.fsys.(WriteFileFS)
+			if !ok {
+				return Throw(MakeError(&fs.PathError{Op: "write", Path: fname.value, Err: errors.ErrUnsupported}))
+			}
+
+			if err := wfs.WriteFile(fname.value, []byte(content.value), 0666); err != nil {
+				return Throw(MakeError(err))
+			}
+
+			return content
+		},
+
+		//
+		// sleep ms
+		//
+		"sleep": func(env *Env, args []any) any {
+			if len(args) == 0 {
+
+				return ErrMissing
+			}
+
+			v := env.Get(args[0])
+
+			if tm, ok := v.(CanInt); ok {
+				env.sleep(time.Millisecond * time.Duration(tm.Int()))
+				return tm
+			}
+
+			return invalidType(v)
 		},
 
 		//
@@ -720,12 +1181,17 @@ func init() {
 		"rand": func(env *Env, args []any) any {
 			switch len(args) {
 			case 0:
-				return rand.Float64()
+				return Float{value: rand.Float64()}
 
 			case 1:
 				v := env.Get(args[0])
 				if v, ok := v.(CanInt); ok {
-					return rand.Int63n(v.Int())
+					n := v.Int()
+					if n <= 0 {
+						return Throw(MakeError(fmt.Errorf("%w: %v", ErrOutOfRange, n)))
+					}
+
+					return Integer{value: rand.Int63n(n)}
 				}
 
 				return invalidType(v)
@@ -734,8 +1200,6 @@ func init() {
 				n := rand.Intn(len(args))
 				return args[n]
 			}
-
-			return Nil
 		},
 
 		//
@@ -752,16 +1216,23 @@ func init() {
 			switch t := s.(type) {
 			case String:
 				if ss, ok := n.(String); ok {
-					if p := strings.Index(t.String(), ss.String()); p > 0 {
-						return p
+					if p := strings.Index(t.value, ss.value); p >= 0 {
+						return MakeInt(utf8.RuneCountInString(t.value[:p])) // rune index
 					}
 
 					return Nil
 				}
 
 			case List:
-				if p := slices.Index(t.items, n); p > 0 {
-					return p
+				if p := slices.IndexFunc(t.items, func(v any) bool { return equal(n, v) }); p >= 0 {
+					return MakeInt(p)
+				}
+
+				return Nil
+
+			case Vector:
+				if p := slices.IndexFunc(t.Items(), func(v any) bool { return equal(n, v) }); p >= 0 {
+					return MakeInt(p)
 				}
 
 				return Nil
@@ -788,14 +1259,17 @@ func init() {
 				}
 
 			case List:
-				return Boolean{value: slices.Contains(t.items, n)}
+				return Boolean{value: slices.ContainsFunc(t.items, func(v any) bool { return equal(n, v) })}
+
+			case Vector:
+				return Boolean{value: slices.ContainsFunc(t.Items(), func(v any) bool { return equal(n, v) })}
 			}
 
 			return ErrInvalidType
 		},
 
 		//
-		// append string string... | list list...
+		// append string string... | list list... | vector vector...
 		//
 		"append": func(env *Env, args []any) any {
 			if len(args) == 0 {
@@ -822,15 +1296,37 @@ func init() {
 
 			case List:
 				for _, v := range args[1:] {
-					ll, ok := v.(List)
-					if !ok {
+					switch ll := v.(type) {
+					case List:
+						t.items = append(t.items, ll.items...)
+
+					case Vector:
+						t.items = append(t.items, ll.Items()...)
+
+					default:
 						return ErrInvalidType
 					}
-
-					t.items = append(t.items, ll.items...)
 				}
 
 				return t
+
+			case Vector:
+				items := slices.Clone(t.Items())
+
+				for _, v := range args[1:] {
+					switch ll := v.(type) {
+					case List:
+						items = append(items, ll.items...)
+
+					case Vector:
+						items = append(items, ll.Items()...)
+
+					default:
+						return ErrInvalidType
+					}
+				}
+
+				return MakeVector(items...)
 			}
 
 			return ErrInvalidType
@@ -864,6 +1360,69 @@ func init() {
 			return
 		},
 
+		//
+		// error fmt args...
+		//
+		"error": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			f, args := env.Get(args[0]), env.GetValues(args[1:])
+
+			sfmt, ok := f.(String)
+			if !ok {
+				return invalidType(f)
+			}
+
+			return Throw(MakeError(errors.New(fmt.Sprintf(sfmt.value, args...))))
+		},
+
+		//
+		// throw value
+		//
+		"throw": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			return Throw(env.Get(args[0]))
+		},
+
+		//
+		// try stmt... [(catch e stmt...)] [(finally stmt...)]
+		//
+		"try": func(env *Env, args []any) any {
+			var body []any
+			var catch, finally *List
+
+			for _, v := range args {
+				if l, ok := v.(List); ok && len(l.items) > 0 {
+					if s, ok := l.items[0].(Symbol); ok {
+						switch s.value {
+						case "catch":
+							catch = &l
+							continue
+
+						case "finally":
+							finally = &l
+							continue
+						}
+					}
+				}
+
+				body = append(body, v)
+			}
+
+			if finally != nil {
+				defer func() {
+					resolve(evalBody(env, finally.items[1:]))
+				}()
+			}
+
+			return evalTry(env, body, catch)
+		},
+
 		//
 		// not bool
 		//
@@ -936,7 +1495,7 @@ func init() {
 						return barg
 					}
 
-					return env.Get(args[0])
+					return tailCall{env: env, expr: args[0]}
 				}
 
 				// else
@@ -945,7 +1504,7 @@ func init() {
 					return Nil
 
 				case 2: // return else expr
-					return env.Get(args[1])
+					return tailCall{env: env, expr: args[1]}
 				}
 
 				// else if
@@ -965,8 +1524,10 @@ func init() {
 			cond, args := args[0], args[1:]
 
 			for {
+				env.check()
+
 				bval, ok := env.Get(cond).(CanBool)
-				if Verbose {
+				if env.verbose() {
 					fmt.Println(cond, bval)
 				}
 
@@ -974,11 +1535,9 @@ func init() {
 					break
 				}
 
-				for _, v := range args {
-					if Verbose {
-						fmt.Println("  ", v)
-					}
-					ret = Eval(env, v)
+				var stop bool
+				if ret, stop = evalLoopBody(env, args); stop {
+					break
 				}
 			}
 
@@ -988,21 +1547,14 @@ func init() {
 		//
 		// begin stmt...
 		//
-		"begin": func(env *Env, args []any) (ret any) {
-			for _, v := range args {
-				if Verbose {
-					fmt.Println("  ", v)
-				}
-				ret = Eval(env, v)
-			}
-
-			return
+		"begin": func(env *Env, args []any) any {
+			return evalBody(env, args)
 		},
 
 		//
 		// let (locals) stmt...
 		//
-		"let": func(env *Env, args []any) (ret any) {
+		"let": func(env *Env, args []any) any {
 			if len(args) == 0 {
 				return ErrMissing
 			}
@@ -1019,14 +1571,7 @@ func init() {
 				env.PutLocal(n, nil)
 			}
 
-			for _, v := range args {
-				if Verbose {
-					fmt.Println("  ", v)
-				}
-				ret = Eval(env, v)
-			}
-
-			return
+			return evalBody(env, args)
 		},
 
 		//
@@ -1038,7 +1583,7 @@ func init() {
 			}
 
 			e := env.Get(args[0])
-			return Eval(env, e)
+			return tailCall{env: env, expr: e}
 		},
 
 		//
@@ -1055,14 +1600,74 @@ func init() {
 				return invalidType(params)
 			}
 
-			return Lambda{args: pparams.items, body: args}
+			p, err := parseParams(pparams.items)
+			if err != nil {
+				return Throw(MakeError(err))
+			}
+
+			return Lambda{args: pparams.items, params: p, body: args, env: env}
+		},
+
+		//
+		// defmacro name (args) stmt...
+		//
+		"defmacro": func(env *Env, args []any) any {
+			if len(args) < 2 {
+				return ErrMissing
+			}
+
+			name, ok := args[0].(Symbol)
+			if !ok {
+				return invalidType(args[0])
+			}
+
+			params, ok := args[1].(List)
+			if !ok {
+				return invalidType(args[1])
+			}
+
+			p, err := parseParams(params.items)
+			if err != nil {
+				return Throw(MakeError(err))
+			}
+
+			env.Put(name, Macro{name: name.value, args: params.items, params: p, body: args[2:], env: env})
+			return name
+		},
+
+		//
+		// macroexpand-1 form
+		//
+		"macroexpand-1": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			form, _ := macroexpand1(env, env.Get(args[0]))
+			return form
+		},
+
+		//
+		// macroexpand form
+		//
+		"macroexpand": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			form, expanded := env.Get(args[0]), true
+			for expanded {
+				form, expanded = macroexpand1(env, form)
+			}
+
+			return form
 		},
 
 		//
 		// list items...
 		//
 		"list": func(env *Env, args []any) any {
-			return List{items: args}
+			return List{items: env.GetList(args)}
 		},
 
 		//
@@ -1073,16 +1678,24 @@ func init() {
 				return ErrMissing
 			}
 
-			l, ok := env.Get(args[0]).(List)
-			if !ok {
+			var items []any
+
+			switch l := env.Get(args[0]).(type) {
+			case List:
+				items = l.items
+
+			case Vector:
+				items = l.Items()
+
+			default:
 				return invalidType(args[0])
 			}
 
-			if len(l.items) == 0 {
+			if len(items) == 0 {
 				return Nil
 			}
 
-			return l.items[0]
+			return items[0]
 		},
 
 		//
@@ -1113,8 +1726,11 @@ func init() {
 				return ErrMissing
 			}
 
-			n, ok := env.Get(args[0]).(CanInt)
-			if !ok {
+			n := env.Get(args[0])
+
+			switch n.(type) {
+			case Integer, BigInt: // a BigInt is always out of range
+			default:
 				return invalidType(args[0])
 			}
 
@@ -1123,13 +1739,12 @@ func init() {
 				return invalidType(args[1])
 			}
 
-			nn := int(n.Int())
-
-			if nn < 0 || nn >= len(l.items) {
+			i, ok := n.(Integer)
+			if !ok || i.value < 0 || i.value >= int64(len(l.items)) {
 				return Nil
 			}
 
-			return l.items[nn]
+			return l.items[i.value]
 		},
 
 		//
@@ -1140,112 +1755,211 @@ func init() {
 				return ErrMissing
 			}
 
-			l, ok := env.Get(args[0]).(List)
-			if !ok {
-				return invalidType(args[0])
-			}
+			switch l := env.Get(args[0]).(type) {
+			case List:
+				if len(l.items) == 0 {
+					return Nil
+				}
 
-			if len(l.items) == 0 {
-				return Nil
+				return List{items: l.items[1:]}
+
+			case Vector:
+				if l.Len() == 0 {
+					return Nil
+				}
+
+				return MakeVector(l.Items()[1:]...)
 			}
 
-			return List{items: l.items[1:]}
+			return invalidType(args[0])
 		},
+	})
+}
+
+// macroexpand1 expands form once, if it is a macro call.
+func macroexpand1(env *Env, form any) (any, bool) {
+	l, ok := form.(List)
+	if !ok || len(l.items) == 0 {
+		return form, false
 	}
+
+	name, ok := l.items[0].(Symbol)
+	if !ok {
+		return form, false
+	}
+
+	m, ok := env.Get(name).(Macro) // a builtin, unless shadowed by a macro
+	if !ok {
+		return form, false
+	}
+
+	return m.expand(env, l.items[1:]), true
 }
 
-// CallLambda call a lambda function, passing the local enviroment and some input parameters
-func CallLambda(l Lambda, env *Env, args []any) (ret any) {
-	lenv := NewEnv(env)
+// quasiquote expands a quasi-quoted template, evaluating the unquoted and spliced expressions.
+// Nested quasi-quoted templates increase the depth, and are only expanded at the outer level.
+func quasiquote(env *Env, v any, depth int) any {
+	switch t := v.(type) {
+	case Unquoted:
+		if depth == 1 {
+			return env.Get(t.value)
+		}
 
-	for i, n := range l.args {
-		var v any = nil
+		return Unquoted{value: quasiquote(env, t.value, depth-1)}
 
-		if i < len(args) {
-			v = lenv.PutLocal(n, env.Get(args[i]))
+	case Spliced:
+		if depth == 1 { // not in a list
+			return invalidType(t)
 		}
 
-		lenv.PutLocal(n, v)
-	}
+		return Spliced{value: quasiquote(env, t.value, depth-1)}
 
-	for _, v := range l.body {
-		if Verbose {
-			fmt.Println("  ", v)
+	case Quasiquoted:
+		return Quasiquoted{value: quasiquote(env, t.value, depth+1)}
+
+	case Quoted:
+		return Quoted{value: quasiquote(env, t.value, depth)}
+
+	case List:
+		items := make([]any, 0, len(t.items))
+
+		for _, item := range t.items {
+			if s, ok := item.(Spliced); ok && depth == 1 {
+				switch sv := env.Get(s.value).(type) {
+				case List:
+					items = append(items, sv.items...)
+
+				case Boolean:
+					if sv.value { // nil is the empty list
+						return invalidType(sv)
+					}
+
+				default:
+					return invalidType(sv)
+				}
+
+				continue
+			}
+
+			items = append(items, quasiquote(env, item, depth))
 		}
-		ret = Eval(lenv, v)
+
+		return List{items: items}
 	}
 
-	return
+	return v
 }
 
-func callop(op Op, env *Env, args []any) any {
-	if len(args) == 0 {
-		if op.value == "+" {
-			return 0
+// tailCall is returned by builtins (and lambda calls) that want Eval to evaluate
+// an expression in tail position. Eval loops on it instead of recursing,
+// so that tail calls run in constant stack space.
+type tailCall struct {
+	env  *Env
+	expr any
+}
+
+// evalBody evaluates all the statements in body but the last one,
+// that is returned as a tailCall.
+func evalBody(env *Env, body []any) any {
+	if len(body) == 0 {
+		return nil
+	}
+
+	last := len(body) - 1
+
+	for _, v := range body[:last] {
+		if env.verbose() {
+			fmt.Println("  ", v)
 		}
+		Eval(env, v)
+	}
 
-		return ErrMissing
+	if env.verbose() {
+		fmt.Println("  ", body[last])
 	}
+	return tailCall{env: env, expr: body[last]}
+}
 
-	first := env.Get(args[0])
+// bindLambda binds the input parameters (evaluated in the caller environment)
+// in a new environment linked to the one where the lambda was defined.
+func bindLambda(l Lambda, env *Env, args []any) *Env {
+	parent := l.env
+	if parent == nil {
+		parent = env
+	}
 
-	switch t := first.(type) {
-	case Integer:
-		v := t.value
+	values := env.GetList(args)
 
-		for _, a := range args[1:] {
-			a = env.Get(a)
+	lenv := NewEnv(parent)
+	lenv.run = env.run
 
-			ii, ok := a.(CanInt)
-			if !ok {
-				return invalidType(a)
-			}
+	name := l.name
+	if name == "" {
+		name = "lambda"
+	}
 
-			switch op.value {
-			case "+":
-				v += ii.Int()
-			case "-":
-				v -= ii.Int()
-			case "*":
-				v *= ii.Int()
-			case "/":
-				v /= ii.Int()
-			case "%":
-				v %= ii.Int()
-			}
+	if l.params == nil { // not created by the lambda builtin
+		p, err := parseParams(l.args)
+		if err != nil {
+			Throw(MakeError(err))
 		}
 
-		return Integer{value: v}
+		l.params = p
+	}
+
+	l.params.bind(lenv, name, values)
+	return lenv
+}
 
-	case Float:
-		v := t.value
+// resolve evaluates the expression in a tailCall, or returns the input value
+func resolve(v any) any {
+	if tc, ok := v.(tailCall); ok {
+		return Eval(tc.env, tc.expr)
+	}
 
-		for _, a := range args[1:] {
-			a = env.Get(a)
+	return v
+}
 
-			ii, ok := a.(CanFloat)
-			if !ok {
-				return invalidType(a)
-			}
+// CallLambda call a lambda function, passing the local enviroment and some input parameters.
+// The arguments are evaluated in the caller environment, while the body is evaluated
+// in a new environment linked to the one where the lambda was defined.
+// The call counts for the depth limit of the current evaluation (see EvalContext).
+func CallLambda(l Lambda, env *Env, args []any) any {
+	lenv := bindLambda(l, env, args)
 
-			switch op.value {
-			case "+":
-				v += ii.Float()
-			case "-":
-				v -= ii.Float()
-			case "*":
-				v *= ii.Float()
-			case "/":
-				v /= ii.Float()
-			case "%":
-				v = float64(int64(v) % int64(ii.Float()))
-			}
+	if run := env.run; run != nil {
+		run.enter()
+		defer run.exit()
+	}
+
+	defer exitLambda()
+
+	if l.block {
+		return evalBlock(lenv, l.name, l.body)
+	}
+
+	return resolve(evalBody(lenv, l.body))
+}
+
+func callop(op Op, env *Env, args []any) any {
+	if len(args) == 0 {
+		if op.value == "+" {
+			return Integer{value: 0}
 		}
 
-		return Float{value: v}
+		return Throw(ErrMissing)
+	}
+
+	v := env.Get(args[0])
+	if numrank(v) == rankNone {
+		return invalidType(v)
 	}
 
-	return invalidType(first)
+	for _, a := range args[1:] {
+		v = arith(op.value, v, env.Get(a))
+	}
+
+	return v
 }
 
 func callcond(op Cond, env *Env, args []any) any {
@@ -1253,8 +1967,18 @@ func callcond(op Cond, env *Env, args []any) any {
 		return True
 	}
 
-	c1, ok := env.Get(args[0]).(CanCompare)
+	first := env.Get(args[0])
+
+	c1, ok := first.(CanCompare)
 	if !ok {
+		if op.value == "=" { // lists, maps...
+			for _, a := range args[1:] {
+				if !equal(first, env.Get(a)) {
+					return Nil
+				}
+			}
+		}
+
 		return True
 	}
 
@@ -1294,14 +2018,97 @@ func callcond(op Cond, env *Env, args []any) any {
 
 // Env stores the current environments (collection of variables)
 type Env struct {
-	vars map[string]any
-	next *Env
+	vars   map[string]any
+	next   *Env
+	interp *Interpreter
+	run    *evalState // set for evaluations started with EvalContext
 }
 
 // NewEnv creates a new enviroment.
 // The root environment should have prev=nil, local environment will link to the previous (parent) one.
+// A root environment created with NewEnv uses the builtins of the default interpreter.
 func NewEnv(prev *Env) *Env {
-	return &Env{vars: map[string]any{}, next: prev}
+	if prev == nil {
+		return &Env{vars: map[string]any{}, interp: Default()}
+	}
+
+	return &Env{vars: map[string]any{}, next: prev, interp: prev.interp, run: prev.run}
+}
+
+// Interpreter returns the interpreter this environment belongs to
+func (e *Env) Interpreter() *Interpreter {
+	return e.interp
+}
+
+func (e *Env) verbose() bool {
+	return Verbose || e.interp.verbose
+}
+
+// check throws an error if the current evaluation has been canceled
+func (e *Env) check() {
+	if e.run != nil {
+		e.run.check()
+	}
+}
+
+// sleep waits for the specified duration, or until the current evaluation is canceled
+func (e *Env) sleep(d time.Duration) {
+	if e.run == nil {
+		time.Sleep(d)
+		return
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-e.run.ctx.Done():
+		e.run.check()
+	}
+}
+
+// evalState is the state of an evaluation started with EvalContext.
+// It is shared by all the environments created during the evaluation.
+type evalState struct {
+	ctx      context.Context
+	steps    int64
+	maxSteps int64
+	depth    int
+	maxDepth int
+}
+
+// check throws an error if the context is done
+func (s *evalState) check() {
+	select {
+	case <-s.ctx.Done():
+		Throw(MakeError(s.ctx.Err()))
+	default:
+	}
+}
+
+// step counts an evaluation step, throwing ErrStepLimit if there are too many
+func (s *evalState) step() {
+	s.steps++
+
+	if s.maxSteps > 0 && s.steps > s.maxSteps {
+		Throw(ErrStepLimit)
+	}
+}
+
+// enter is called for nested lambda calls, throwing ErrDepthLimit if there are too many
+func (s *evalState) enter() {
+	s.check()
+
+	if s.maxDepth > 0 && s.depth >= s.maxDepth {
+		Throw(ErrDepthLimit)
+	}
+
+	s.depth++
+}
+
+func (s *evalState) exit() {
+	s.depth--
 }
 
 func getname(o any) (string, error) {
@@ -1359,6 +2166,14 @@ func (e *Env) Get(o any) any {
 		return e.next.Get(o)
 	}
 
+	if strings.HasPrefix(name, ":") { // keywords evaluate to themselves
+		return Symbol{value: name}
+	}
+
+	if f, ok := e.interp.builtin(name); ok { // unbound builtin names evaluate to the builtin
+		return Builtin{name: name, call: f}
+	}
+
 	return Nil
 }
 
@@ -1385,6 +2200,51 @@ func (e *Env) GetValues(l []any) (el []any) {
 	return
 }
 
+// equal compares two objects: symbols by name, lists by items, and comparable objects with Eq
+func equal(a, b any) bool {
+	switch t := a.(type) {
+	case Symbol:
+		s, ok := b.(Symbol)
+		return ok && t.value == s.value
+
+	case List:
+		l, ok := b.(List)
+		return ok && slices.EqualFunc(t.items, l.items, equal)
+
+	case Quoted:
+		q, ok := b.(Quoted)
+		return ok && equal(t.value, q.value)
+
+	case Boolean:
+		o, ok := b.(Boolean)
+		return ok && t.value == o.value
+
+	case CanCompare:
+		return t.Eq(b)
+
+	case Map:
+		m, ok := b.(Map)
+		return ok && t.equal(m)
+
+	case Vector:
+		v, ok := b.(Vector)
+		return ok && slices.EqualFunc(t.Items(), v.Items(), equal)
+
+	case Builtin:
+		f, ok := b.(Builtin)
+		return ok && t.name == f.name
+
+	case Regexp:
+		r, ok := b.(Regexp)
+		return ok && t.value.String() == r.value.String()
+
+	case Lambda, Macro:
+		return false
+	}
+
+	return a == b
+}
+
 // AsBool converts the input object to a boolean, if possible or return the default value.
 func AsBool(o any, def bool) bool {
 	if i, ok := o.(CanBool); ok {
@@ -1448,6 +2308,11 @@ func MakeString(v string) String {
 	return String{value: v}
 }
 
+// MakeSymbol creates a Symbol object from a name
+func MakeSymbol(name string) Symbol {
+	return Symbol{value: name}
+}
+
 // MakeList creates a List object from a list of objects
 func MakeList(items ...any) List {
 	return List{items: items}
@@ -1458,54 +2323,214 @@ func MakeError(e error) Error {
 	return Error{value: e}
 }
 
-// Eval evaluates the current object
-func Eval(env *Env, v any) any {
-	if Verbose {
-		fmt.Println("eval", v)
+// EvalE evaluates the current object, returning the uncaught thrown objects as errors.
+func EvalE(env *Env, v any) (ret any, err error) {
+	defer catchError(&ret, &err)
+
+	return Eval(env, v), nil
+}
+
+// CallE calls a function (a lambda or a builtin) with a list of evaluated arguments.
+// As EvalE, the uncaught thrown objects are returned as errors.
+func CallE(env *Env, f any, args ...any) (ret any, err error) {
+	defer catchError(&ret, &err)
+
+	return apply(env, f, args), nil
+}
+
+// catchError recovers the uncaught thrown objects (and the escaped jumps) and returns them as errors.
+// It must be called with defer.
+func catchError(ret *any, err *error) {
+	if r := recover(); r != nil {
+		if j, ok := r.(jump); ok { // break, continue or return-from outside of their target
+			*ret, *err = nil, j.error()
+			return
+		}
+
+		t, ok := r.(thrown)
+		if !ok {
+			panic(r)
+		}
+
+		*ret, *err = nil, t.error()
 	}
+}
 
-	switch t := v.(type) {
-	case String:
-		return t
+// EvalContext evaluates the current object, checking for the context cancellation
+// and the step and call depth limits of the interpreter (see WithMaxSteps and WithMaxDepth).
+// The uncaught thrown objects and the exceeded limits are returned as errors.
+func EvalContext(ctx context.Context, env *Env, v any) (any, error) {
+	renv := NewEnv(env)
+	renv.run = &evalState{ctx: ctx, maxSteps: env.interp.maxSteps, maxDepth: env.interp.maxDepth}
 
-	case Integer:
-		return t
+	return EvalE(renv, v)
+}
 
-	case Float:
-		return t
+// Eval evaluates the current object.
+// Uncaught thrown objects (see Throw) are propagated as panics, use EvalE to get them as errors.
+// Expressions in tail position (returned as tailCall by builtins and lambda calls)
+// are evaluated in a loop, without growing the stack.
+func Eval(env *Env, v any) (result any) {
+	var form List   // the list currently evaluated
+	var frame Frame // the lambda currently called
+	var called bool
+	var entered *evalState
+	var blocks []string // the blocks of the functions called in tail position
 
-	case Boolean:
-		return t
+	defer func() {
+		if entered != nil {
+			entered.exit()
+		}
 
-	case Quoted:
-		return t.value
+		if form.items == nil {
+			return
+		}
 
-	case Symbol:
-		return env.Get(t)
+		if r := recover(); r != nil {
+			if j, ok := r.(jump); ok {
+				switch {
+				case j.kind == "return-from" && slices.Contains(blocks, j.block):
+					// the functions are called in tail position, so their value is the value of the whole evaluation
+					result = j.value
+					return
 
-	case List:
-		if len(t.items) == 0 {
-			return Nil
+				case j.kind != "return-from" && called: // break and continue can't exit a function
+					r = thrown{value: j.error()}
+				}
+			}
+
+			t, ok := r.(thrown)
+			if !ok {
+				panic(r)
+			}
+
+			if len(t.trace) == 0 {
+				// where the error happened
+				t.trace = append(t.trace, Frame{Name: callname(form), Pos: form.Pos()})
+			}
+			if called {
+				t.trace = append(t.trace, frame)
+			}
+
+			panic(t)
 		}
-		switch i := t.items[0].(type) {
+	}()
+
+	for {
+		if env.verbose() {
+			fmt.Println("eval", v)
+		}
+
+		if env.run != nil {
+			env.run.step()
+		}
+
+		var ret any
+
+		switch t := v.(type) {
+		case String:
+			return t
+
+		case Integer:
+			return t
+
+		case Float:
+			return t
+
+		case Boolean:
+			return t
+
+		case Quoted:
+			return t.value
+
+		case Quasiquoted:
+			return quasiquote(env, t.value, 1)
+
+		case Map:
+			return t.eval(env)
+
+		case Vector:
+			return MakeVector(env.GetList(t.Items())...)
+
 		case Symbol:
-			if f, ok := builtins[i.value]; ok {
-				return f(env, t.items[1:])
+			return env.Get(t)
+
+		case List:
+			if len(t.items) == 0 {
+				return Nil
 			}
-			v := env.Get(i)
-			if l, ok := v.(Lambda); ok {
-				return CallLambda(l, env, t.items[1:])
+
+			form = t
+
+			var fn any
+			var name string
+
+			switch i := t.items[0].(type) {
+			case Symbol:
+				fn, name = env.Get(i), i.value // the variables shadow the builtins
+
+			case List: // computed head, i.e. ((lambda (x) x) 5)
+				fn, name = Eval(env, i), "lambda"
+
+			default:
+				fn = i
 			}
 
-			return v
+			switch f := fn.(type) {
+			case Builtin:
+				ret = f.call(env, t.items[1:])
+				if failed(ret) {
+					Throw(ret)
+				}
+
+			case Lambda:
+				lenv := bindLambda(f, env, t.items[1:])
+				if run := env.run; run != nil {
+					if entered == nil {
+						run.enter()
+						entered = run
+					} else {
+						run.check()
+					}
+				}
+
+				if f.block && !slices.Contains(blocks, f.name) {
+					blocks = append(blocks, f.name)
+				}
 
-		case Op:
-			return callop(i, env, t.items[1:])
+				frame, called = Frame{Name: name, Pos: t.Pos()}, true
+				ret = evalBody(lenv, f.body)
 
-		case Cond:
-			return callcond(i, env, t.items[1:])
+			case Macro:
+				ret = tailCall{env: env, expr: f.expand(env, t.items[1:])}
+
+			case Op:
+				return callop(f, env, t.items[1:])
+
+			case Cond:
+				return callcond(f, env, t.items[1:])
+
+			default:
+				switch t.items[0].(type) {
+				case Symbol:
+					return fn // the value of the variable
+
+				case List:
+					return Throw(MakeError(fmt.Errorf("%w: %v is not a function", ErrInvalidType, fn)))
+				}
+
+				return v
+			}
+
+		default:
+			return v
 		}
-	}
 
-	return v
+		tc, ok := ret.(tailCall)
+		if !ok {
+			return ret
+		}
+
+		env, v = tc.env, tc.expr
+	}
 }
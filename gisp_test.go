@@ -0,0 +1,86 @@
+package gisp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCallE(t *testing.T) {
+	it := New()
+
+	l, err := NewParser(strings.NewReader(`(lambda (x) (if (> x 0) x (throw "negative")))`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := it.EvalE(l[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ret, err := CallE(it.Env(), f, MakeInt(1)); err != nil || !equal(ret, MakeInt(1)) {
+		t.Errorf("expected 1, got %v %v", ret, err)
+	}
+
+	if _, err := CallE(it.Env(), f, MakeInt(-1)); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("expected the thrown error, got %v", err)
+	}
+
+	if _, err := CallE(it.Env(), MakeInt(1)); err == nil {
+		t.Error("expected an error calling a number")
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	src := `(defun inner (x)
+  (/ x 0))
+(defun outer (x)
+  (+ 1 (inner x)))
+(outer 5)`
+
+	_, err := run(t, src)
+
+	var everr EvalError
+	if !errors.As(err, &everr) {
+		t.Fatalf("expected an EvalError, got %v", err)
+	}
+
+	if !errors.Is(err, ErrDivByZero) {
+		t.Errorf("expected %v, got %v", ErrDivByZero, everr.Err)
+	}
+
+	expected := []struct {
+		name         string
+		line, column int
+	}{
+		{"/", 2, 3},     // where the error happened
+		{"inner", 4, 8}, // the calls, from the innermost
+		{"outer", 5, 1},
+	}
+
+	if len(everr.Trace) != len(expected) {
+		t.Fatalf("expected %d frames, got %v", len(expected), everr.Trace)
+	}
+
+	for i, f := range everr.Trace {
+		if e := expected[i]; f.Name != e.name || f.Pos.Line != e.line || f.Pos.Column != e.column {
+			t.Errorf("frame %d: expected %v at %d:%d, got %v", i, e.name, e.line, e.column, f)
+		}
+	}
+}
+
+func TestJumpOutsideOfTarget(t *testing.T) {
+	for _, src := range []string{
+		`(break)`,
+		`(continue)`,
+		`(return-from outer 1)`,
+		`(block inner (return-from outer 1))`,
+		`(dotimes (i 3) (funcall (lambda () (break))))`,
+		`(defun skip () (continue) 1) (dolist (x '(1 2)) (skip))`,
+	} {
+		if ret, err := run(t, src); !errors.Is(err, ErrJump) {
+			t.Errorf("%v: expected %v, got %v %v", src, ErrJump, ret, err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package gisp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Interpreter is an isolated gisp interpreter, with its own builtins, global environment, options and I/O.
+//
+// Different interpreters can be used concurrently, but the evaluation in a single interpreter
+// (i.e. in its environments) is not safe for concurrent use.
+type Interpreter struct {
+	mu       sync.RWMutex
+	builtins map[string]builtin
+
+	env      *Env
+	verbose  bool
+	stdin    io.Reader
+	stdout   io.Writer
+	maxSteps int64
+	maxDepth int
+	granted  Capability
+	fsys     fs.FS
+	modules  []Module
+
+	remu    sync.Mutex
+	regexps map[string]*regexp.Regexp // compiled patterns cache
+}
+
+// builtin is a builtin method, with the capabilities it requires
+type builtin struct {
+	call Call
+	caps Capability
+}
+
+// Option is a configuration option for New
+type Option func(it *Interpreter)
+
+// WithVerbose enables the verbose (debug) output
+func WithVerbose(verbose bool) Option {
+	return func(it *Interpreter) {
+		it.verbose = verbose
+	}
+}
+
+// WithStdin sets the input used by the builtins that read from the standard input (readfile, readlines)
+func WithStdin(r io.Reader) Option {
+	return func(it *Interpreter) {
+		it.stdin = r
+	}
+}
+
+// WithStdout sets the output used by the builtins that write to the standard output (print, println)
+func WithStdout(w io.Writer) Option {
+	return func(it *Interpreter) {
+		it.stdout = w
+	}
+}
+
+// WithMaxSteps sets the maximum number of evaluation steps for EvalContext (0 for no limit)
+func WithMaxSteps(n int64) Option {
+	return func(it *Interpreter) {
+		it.maxSteps = n
+	}
+}
+
+// WithMaxDepth sets the maximum depth of nested (non tail) calls for EvalContext (0 for no limit)
+func WithMaxDepth(n int) Option {
+	return func(it *Interpreter) {
+		it.maxDepth = n
+	}
+}
+
+// WithCapabilities sets the capabilities granted to the builtins (the default is CapAll).
+// Calling a builtin that requires a capability that was not granted throws ErrPermission.
+func WithCapabilities(caps Capability) Option {
+	return func(it *Interpreter) {
+		it.granted = caps
+	}
+}
+
+// WithFS sets the file system accessed by the builtins that read (and write) files.
+// The default gives access to all the host files.
+// To allow writing, the file system should implement WriteFileFS.
+func WithFS(fsys fs.FS) Option {
+	return func(it *Interpreter) {
+		it.fsys = fsys
+	}
+}
+
+// WithModules loads a set of optional modules (see Interpreter.Load)
+func WithModules(mods ...Module) Option {
+	return func(it *Interpreter) {
+		it.modules = append(it.modules, mods...)
+	}
+}
+
+// New creates a new Interpreter, with the core builtins and an empty global environment.
+func New(opts ...Option) *Interpreter {
+	it := &Interpreter{
+		builtins: make(map[string]builtin, len(builtins)),
+		stdin:    os.Stdin,
+		stdout:   os.Stdout,
+		granted:  CapAll,
+		fsys:     osFS{},
+	}
+
+	for name, call := range builtins {
+		it.builtins[name] = builtin{call: call, caps: capabilities[name]}
+	}
+
+	for _, opt := range opts {
+		opt(it)
+	}
+
+	it.env = &Env{vars: map[string]any{}, interp: it}
+
+	mods := it.modules
+	it.modules = nil
+
+	for _, m := range mods {
+		it.Load(m)
+	}
+
+	return it
+}
+
+// Module is a set of optional builtins and global variables, that can be loaded in an interpreter.
+type Module struct {
+	Name     string
+	Builtins map[string]Call
+	Vars     map[string]any
+}
+
+// Load adds the builtins and the global variables of a module to the interpreter.
+// Note that it can override existing builtin methods and variables.
+func (it *Interpreter) Load(m Module) {
+	for name, call := range m.Builtins {
+		it.AddBuiltin(name, call)
+	}
+
+	for name, value := range m.Vars {
+		it.env.PutLocal(name, value)
+	}
+
+	it.mu.Lock()
+	it.modules = append(it.modules, m)
+	it.mu.Unlock()
+}
+
+// Modules returns the names of the loaded modules
+func (it *Interpreter) Modules() (l []string) {
+	it.mu.RLock()
+	defer it.mu.RUnlock()
+
+	for _, m := range it.modules {
+		l = append(l, m.Name)
+	}
+
+	return
+}
+
+// AddBuiltin adds a new built-in method.
+// Note that it can override existing builtin methods.
+func (it *Interpreter) AddBuiltin(name string, value Call) {
+	it.AddBuiltinCap(name, CapNone, value)
+}
+
+// AddBuiltinCap adds a new built-in method, that can only be called if the interpreter was granted the required capabilities.
+// Note that it can override existing builtin methods.
+func (it *Interpreter) AddBuiltinCap(name string, caps Capability, value Call) {
+	it.mu.Lock()
+	it.builtins[name] = builtin{call: value, caps: caps}
+	it.mu.Unlock()
+}
+
+// Granted returns true if the interpreter was granted all the input capabilities
+func (it *Interpreter) Granted(caps Capability) bool {
+	return caps&^it.granted == 0
+}
+
+// Builtins returns a list of builtin method (names)
+// Note that this doesn't return the math and conditional operators.
+func (it *Interpreter) Builtins() (l []string) {
+	it.mu.RLock()
+	defer it.mu.RUnlock()
+
+	for k := range it.builtins {
+		l = append(l, k)
+	}
+
+	return
+}
+
+func (it *Interpreter) builtin(name string) (Call, bool) {
+	it.mu.RLock()
+	b, ok := it.builtins[name]
+	it.mu.RUnlock()
+
+	if ok && !it.Granted(b.caps) {
+		return func(env *Env, args []any) any {
+			return Throw(MakeError(fmt.Errorf("%w: %v requires %v", ErrPermission, name, b.caps&^it.granted)))
+		}, true
+	}
+
+	return b.call, ok
+}
+
+// Env returns the global environment of the interpreter
+func (it *Interpreter) Env() *Env {
+	return it.env
+}
+
+// Eval evaluates the input object in the global environment
+func (it *Interpreter) Eval(v any) any {
+	return Eval(it.env, v)
+}
+
+// EvalE evaluates the input object in the global environment, returning the uncaught errors.
+// The step and call depth limits are applied.
+func (it *Interpreter) EvalE(v any) (any, error) {
+	return EvalContext(context.Background(), it.env, v)
+}
+
+// EvalContext evaluates the input object in the global environment, until the context is canceled.
+// The step and call depth limits are applied.
+func (it *Interpreter) EvalContext(ctx context.Context, v any) (any, error) {
+	return EvalContext(ctx, it.env, v)
+}
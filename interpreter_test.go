@@ -0,0 +1,165 @@
+package gisp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// evalContext parses and evaluates a program in the interpreter, with a context that expires after the timeout
+func evalContext(t *testing.T, it *Interpreter, src string, timeout time.Duration) (ret any, err error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	p := NewParser(strings.NewReader(src))
+	p.SetEnv(it.Env())
+
+	l, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range l {
+		if ret, err = EvalContext(ctx, it.Env(), v); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func TestDepthLimitIndirect(t *testing.T) {
+	// the recursion through funcall and map (that call the lambdas with apply) is limited as the direct one
+	for _, src := range []string{
+		`(defun h (n) (funcall h n)) (h 0)`,
+		`(defun h (n) (apply h (list n))) (h 0)`,
+		`(defun h (n) (map h (list n))) (h 0)`,
+		`(defun h (n) (sort (list 2 1) (lambda (a b) (h n)))) (h 0)`,
+	} {
+		it := New(WithMaxDepth(100))
+
+		if _, err := evalContext(t, it, src, 2*time.Second); !errors.Is(err, ErrDepthLimit) {
+			t.Errorf("%v: expected %v, got %v", src, ErrDepthLimit, err)
+		}
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	it := New(WithMaxDepth(100))
+
+	if ret, err := evalContext(t, it, `(defun f (n) (if (= n 0) 0 (+ 1 (f (- n 1))))) (f 50)`, 2*time.Second); err != nil || !equal(ret, MakeInt(50)) {
+		t.Errorf("expected 50, got %v %v", ret, err)
+	}
+
+	if _, err := evalContext(t, it, `(f 200)`, 2*time.Second); !errors.Is(err, ErrDepthLimit) {
+		t.Errorf("expected %v, got %v", ErrDepthLimit, err)
+	}
+
+	// tail calls don't count
+	if ret, err := evalContext(t, it, `(defun g (n) (if (= n 0) "done" (g (- n 1)))) (g 1000)`, 2*time.Second); err != nil || !equal(ret, String{value: "done"}) {
+		t.Errorf("expected done, got %v %v", ret, err)
+	}
+
+	// the limit can't be caught
+	if _, err := evalContext(t, it, `(try (f 200) (catch e nil))`, 2*time.Second); !errors.Is(err, ErrDepthLimit) {
+		t.Errorf("expected %v, got %v", ErrDepthLimit, err)
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	for _, src := range []string{
+		`(while true 1)`,
+		`(defun loop () (loop)) (loop)`,
+		`(defun loop (n) (funcall loop n)) (loop 0)`,
+		`(map (lambda (x) (while true x)) '(1 2))`,
+	} {
+		it := New(WithMaxSteps(10000))
+
+		if _, err := evalContext(t, it, src, 2*time.Second); !errors.Is(err, ErrStepLimit) {
+			t.Errorf("%v: expected %v, got %v", src, ErrStepLimit, err)
+		}
+	}
+
+	it := New(WithMaxSteps(10000))
+
+	if ret, err := evalContext(t, it, `(+ 1 2)`, 2*time.Second); err != nil || !equal(ret, MakeInt(3)) {
+		t.Errorf("expected 3, got %v %v", ret, err)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	for _, src := range []string{
+		`(while true 1)`,
+		`(defun loop () (loop)) (loop)`,
+		`(defun loop (n) (funcall loop n)) (loop 0)`,
+		`(map (lambda (x) (while true x)) '(1 2))`,
+		`(sleep 10000)`,
+	} {
+		it := New()
+
+		start := time.Now()
+
+		if _, err := evalContext(t, it, src, 100*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%v: expected %v, got %v", src, context.DeadlineExceeded, err)
+		}
+
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%v: stopped after %v", src, d)
+		}
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	it := New()
+
+	l, err := NewParser(strings.NewReader(`(dolist (x '(1 2 3)) (funcall (lambda () (while true x))))`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := EvalContext(ctx, it.Env(), l[0]); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestInterpretersIsolation(t *testing.T) {
+	it1, it2 := New(), New()
+
+	it1.AddBuiltin("only-one", func(env *Env, args []any) any { return MakeInt(1) })
+
+	if _, err := evalContext(t, it1, `(setq x 1) (defun f () "one")`, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if ret, err := evalContext(t, it2, `x`, time.Second); err != nil || !equal(ret, Nil) {
+		t.Errorf("expected x not set, got %v %v", ret, err)
+	}
+
+	if ret, err := evalContext(t, it2, `(f)`, time.Second); err != nil || !equal(ret, Nil) { // undefined
+		t.Errorf("expected f not defined, got %v %v", ret, err)
+	}
+
+	if ret, err := evalContext(t, it2, `(only-one)`, time.Second); err != nil || !equal(ret, Nil) { // undefined
+		t.Errorf("expected only-one not defined, got %v %v", ret, err)
+	}
+
+	if ret, err := evalContext(t, it1, `(list x (f) (only-one))`, time.Second); err != nil || !equal(ret, List{items: []any{MakeInt(1), String{value: "one"}, MakeInt(1)}}) {
+		t.Errorf("expected (1 \"one\" 1), got %v %v", ret, err)
+	}
+
+	// the builtins added to an interpreter don't change the default ones
+	if _, ok := Default().builtin("only-one"); ok {
+		t.Error("only-one added to the default interpreter")
+	}
+}
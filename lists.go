@@ -0,0 +1,453 @@
+package gisp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// quoteArgs wraps a list of evaluated values, so that they are not evaluated again when passed to a function
+func quoteArgs(values []any) []any {
+	args := make([]any, len(values))
+
+	for i, v := range values {
+		args[i] = Quoted{value: v}
+	}
+
+	return args
+}
+
+// maxRangeLen is the maximum length of the lists created by range
+const maxRangeLen = 1 << 24
+
+// rangelen returns the number of items from start to end (excluded) with step,
+// computed without overflowing for any start, end and (non zero) step
+func rangelen(start, end, step int) uint64 {
+	switch {
+	case step > 0 && start < end:
+		return (uint64(end-start)-1)/uint64(step) + 1
+
+	case step < 0 && start > end:
+		return (uint64(start-end)-1)/uint64(-step) + 1
+	}
+
+	return 0
+}
+
+// sortless is the default comparator of sort, throwing an error for objects that can't be compared
+// (the numbers are always comparable, even if one is nan)
+func sortless(a, b any) bool {
+	c, ok := a.(CanCompare)
+	if !ok {
+		invalidType(a)
+	}
+
+	if c.Lt(b) {
+		return true
+	}
+
+	if !c.Eq(b) && !c.Gt(b) && (numrank(a) == rankNone || numrank(b) == rankNone) {
+		Throw(MakeError(fmt.Errorf("%w: cannot compare %v and %v", ErrInvalidType, WriteString(a), WriteString(b))))
+	}
+
+	return false
+}
+
+// apply calls a function (a lambda, a builtin, an operator or the name of a builtin) with a list of evaluated arguments
+func apply(env *Env, f any, values []any) any {
+	args := quoteArgs(values)
+
+	switch t := f.(type) {
+	case Lambda:
+		return CallLambda(t, env, args)
+
+	case Builtin:
+		ret := t.call(env, args)
+		if failed(ret) {
+			Throw(ret)
+		}
+
+		return resolve(ret)
+
+	case Op:
+		return callop(t, env, args)
+
+	case Cond:
+		return callcond(t, env, args)
+
+	case Symbol:
+		switch v := env.Get(t).(type) { // a variable, or a builtin
+		case Lambda, Builtin:
+			return apply(env, v, values)
+		}
+
+	case String:
+		return apply(env, Symbol{value: t.value}, values)
+	}
+
+	return Throw(MakeError(fmt.Errorf("%w: %v is not a function", ErrInvalidType, f)))
+}
+
+// getfunc returns the function in the first argument
+func getfunc(env *Env, args []any) (any, []any) {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	return env.Get(args[0]), args[1:]
+}
+
+// getseq returns the items of the list or vector in the first argument
+func getseq(env *Env, args []any) ([]any, []any) {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	return seqitems(env.Get(args[0])), args[1:]
+}
+
+// seqitems returns the items of a list or vector (nil is the empty list)
+func seqitems(seq any) []any {
+	switch t := seq.(type) {
+	case List:
+		return t.items
+
+	case Vector:
+		return t.Items()
+
+	case Boolean:
+		if !t.value {
+			return nil
+		}
+	}
+
+	invalidType(seq)
+	return nil
+}
+
+// sameseq returns a sequence of the same type of seq (List or Vector) with the input items
+func sameseq(seq any, items []any) any {
+	if _, ok := seq.(Vector); ok {
+		return MakeVector(items...)
+	}
+
+	return List{items: items}
+}
+
+// clamp returns n limited to the range [0, max]
+func clamp(n, max int) int {
+	if n < 0 {
+		return 0
+	}
+
+	if n > max {
+		return max
+	}
+
+	return n
+}
+
+// flatten appends the items of nested lists and vectors to l
+func flatten(l []any, items []any) []any {
+	for _, v := range items {
+		switch t := v.(type) {
+		case List:
+			l = flatten(l, t.items)
+		case Vector:
+			l = flatten(l, t.Items())
+		default:
+			l = append(l, v)
+		}
+	}
+
+	return l
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// map function list... (stops at the end of the shortest list)
+		//
+		"map": func(env *Env, args []any) any {
+			f, args := getfunc(env, args)
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			var lists [][]any
+
+			n := -1
+
+			for len(args) > 0 {
+				var l []any
+
+				l, args = getseq(env, args)
+				lists = append(lists, l)
+
+				if n < 0 || len(l) < n {
+					n = len(l)
+				}
+			}
+
+			result := make([]any, n)
+
+			for i := range result {
+				values := make([]any, len(lists))
+				for j, l := range lists {
+					values[j] = l[i]
+				}
+
+				result[i] = apply(env, f, values)
+			}
+
+			return List{items: result}
+		},
+
+		//
+		// filter predicate list
+		//
+		"filter": func(env *Env, args []any) any {
+			f, args := getfunc(env, args)
+			l, _ := getseq(env, args)
+
+			var result []any
+
+			for _, v := range l {
+				if AsBool(apply(env, f, []any{v}), false) {
+					result = append(result, v)
+				}
+			}
+
+			return List{items: result}
+		},
+
+		//
+		// reduce function list [initial] (without initial value, it starts with the first element)
+		//
+		"reduce": func(env *Env, args []any) any {
+			f, args := getfunc(env, args)
+			l, args := getseq(env, args)
+
+			var acc any
+
+			if len(args) > 0 {
+				acc = env.Get(args[0])
+			} else if len(l) > 0 {
+				acc, l = l[0], l[1:]
+			} else {
+				return Nil
+			}
+
+			for _, v := range l {
+				acc = apply(env, f, []any{acc, v})
+			}
+
+			return acc
+		},
+
+		//
+		// fold function initial list
+		//
+		"fold": func(env *Env, args []any) any {
+			f, args := getfunc(env, args)
+			if len(args) < 2 {
+				return ErrMissing
+			}
+
+			acc := env.Get(args[0])
+			l, _ := getseq(env, args[1:])
+
+			for _, v := range l {
+				acc = apply(env, f, []any{acc, v})
+			}
+
+			return acc
+		},
+
+		//
+		// apply function arg... list
+		//
+		"apply": func(env *Env, args []any) any {
+			f, args := getfunc(env, args)
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			values := env.GetList(args[:len(args)-1])
+			l, _ := getseq(env, args[len(args)-1:])
+
+			return apply(env, f, append(values, l...))
+		},
+
+		//
+		// funcall function arg...
+		//
+		"funcall": func(env *Env, args []any) any {
+			f, args := getfunc(env, args)
+			return apply(env, f, env.GetList(args))
+		},
+
+		//
+		// sort list [less] (stable, the default compares with <)
+		//
+		"sort": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			seq := env.Get(args[0])
+			l := slices.Clone(seqitems(seq))
+
+			var less func(a, b any) bool
+
+			if len(args) > 1 {
+				f := env.Get(args[1])
+				less = func(a, b any) bool { return AsBool(apply(env, f, []any{a, b}), false) }
+			} else {
+				less = sortless
+			}
+
+			sort.SliceStable(l, func(i, j int) bool { return less(l[i], l[j]) })
+			return sameseq(seq, l)
+		},
+
+		//
+		// reverse list
+		//
+		"reverse": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			seq := env.Get(args[0])
+			l := slices.Clone(seqitems(seq))
+
+			slices.Reverse(l)
+			return sameseq(seq, l)
+		},
+
+		//
+		// range end | range start end [step]
+		//
+		"range": func(env *Env, args []any) any {
+			var start, end, step int
+
+			switch len(args) {
+			case 0:
+				return ErrMissing
+
+			case 1:
+				end, step = getint(env, args), 1
+
+			case 2:
+				start, end, step = getint(env, args), getint(env, args[1:]), 1
+
+			default:
+				start, end, step = getint(env, args), getint(env, args[1:]), getint(env, args[2:])
+			}
+
+			if step == 0 {
+				return Throw(MakeError(fmt.Errorf("%w: step 0", ErrOutOfRange)))
+			}
+
+			n := rangelen(start, end, step)
+			if n > maxRangeLen {
+				return Throw(MakeError(fmt.Errorf("%w: range of %v items", ErrOutOfRange, n)))
+			}
+
+			var l []any
+
+			for i := 0; i < int(n); i++ {
+				l = append(l, MakeInt(start+i*step))
+			}
+
+			return List{items: l}
+		},
+
+		//
+		// take n list
+		//
+		"take": func(env *Env, args []any) any {
+			n := getint(env, args)
+			l, _ := getseq(env, args[1:])
+
+			n = clamp(n, len(l))
+			return List{items: slices.Clone(l[:n])}
+		},
+
+		//
+		// drop n list
+		//
+		"drop": func(env *Env, args []any) any {
+			n := getint(env, args)
+			l, _ := getseq(env, args[1:])
+
+			n = clamp(n, len(l))
+			return List{items: slices.Clone(l[n:])}
+		},
+
+		//
+		// zip list... -> ((a1 b1...) (a2 b2...)...) (stops at the end of the shortest list)
+		//
+		"zip": func(env *Env, args []any) any {
+			var lists [][]any
+
+			n := -1
+
+			for len(args) > 0 {
+				var l []any
+
+				l, args = getseq(env, args)
+				lists = append(lists, l)
+
+				if n < 0 || len(l) < n {
+					n = len(l)
+				}
+			}
+
+			result := make([]any, clamp(n, n))
+
+			for i := range result {
+				items := make([]any, len(lists))
+				for j, l := range lists {
+					items[j] = l[i]
+				}
+
+				result[i] = List{items: items}
+			}
+
+			return List{items: result}
+		},
+
+		//
+		// flatten list
+		//
+		"flatten": func(env *Env, args []any) any {
+			l, _ := getseq(env, args)
+			return List{items: flatten(nil, l)}
+		},
+
+		//
+		// count list | count predicate list
+		//
+		"count": func(env *Env, args []any) any {
+			if len(args) == 1 {
+				l, _ := getseq(env, args)
+				return MakeInt(len(l))
+			}
+
+			f, args := getfunc(env, args)
+			l, _ := getseq(env, args)
+
+			n := 0
+
+			for _, v := range l {
+				if AsBool(apply(env, f, []any{v}), false) {
+					n++
+				}
+			}
+
+			return MakeInt(n)
+		},
+	})
+}
@@ -0,0 +1,397 @@
+package gisp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Map is the hash map type.
+// Keys are compared using the gisp equality (i.e. 1 and 1.0 are the same key) and the insertion order is preserved.
+// A Map is a reference type: put modifies the map in place, while assoc, dissoc and merge return a new map.
+type Map struct {
+	m *mapData
+}
+
+type mapData struct {
+	keys   []any
+	values []any
+	index  map[any]int
+}
+
+// MakeMap creates a Map object from a list of key, value pairs
+func MakeMap(kv ...any) Map {
+	m := Map{m: &mapData{index: make(map[any]int, len(kv)/2)}}
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		m.put(kv[i], kv[i+1])
+	}
+
+	return m
+}
+
+func (o Map) String() string { return DisplayString(o) } // {k v ...}
+
+func (o Map) Value() any {
+	m := make(map[any]any, o.Len())
+
+	for i, k := range o.Keys() {
+		v := o.m.values[i]
+		if kobj, ok := k.(Object); ok {
+			k = kobj.Value()
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() { // lists, vectors and maps
+			k = hashkey(o.m.keys[i])
+		}
+		if vobj, ok := v.(Object); ok {
+			v = vobj.Value()
+		}
+
+		m[k] = v
+	}
+
+	return m
+}
+
+func (o Map) Bool() bool { return o.Len() > 0 }
+
+// Len returns the number of entries in the map
+func (o Map) Len() int {
+	if o.m == nil {
+		return 0
+	}
+
+	return len(o.m.keys)
+}
+
+// Get returns the value for the key, if present
+func (o Map) Get(k any) (any, bool) {
+	if o.m == nil {
+		return nil, false
+	}
+
+	i, ok := o.m.index[hashkey(k)]
+	if !ok {
+		return nil, false
+	}
+
+	return o.m.values[i], true
+}
+
+// Keys returns the map keys, in insertion order
+func (o Map) Keys() []any {
+	if o.m == nil {
+		return nil
+	}
+
+	return o.m.keys
+}
+
+// Values returns the map values, in insertion order
+func (o Map) Values() []any {
+	if o.m == nil {
+		return nil
+	}
+
+	return o.m.values
+}
+
+func (o Map) put(k, v any) {
+	h := hashkey(k)
+
+	if i, ok := o.m.index[h]; ok {
+		o.m.values[i] = v
+		return
+	}
+
+	o.m.index[h] = len(o.m.keys)
+	o.m.keys = append(o.m.keys, k)
+	o.m.values = append(o.m.values, v)
+}
+
+// copy returns a new map with the same entries
+func (o Map) copy() Map {
+	m := MakeMap()
+
+	for i, k := range o.Keys() {
+		m.put(k, o.m.values[i])
+	}
+
+	return m
+}
+
+// eval evaluates the keys and values of a map literal
+func (o Map) eval(env *Env) Map {
+	m := MakeMap()
+
+	for i, k := range o.Keys() {
+		m.put(env.Get(k), env.Get(o.m.values[i]))
+	}
+
+	return m
+}
+
+func (o Map) equal(m Map) bool {
+	if o.Len() != m.Len() {
+		return false
+	}
+
+	for i, k := range o.Keys() {
+		if v, ok := m.Get(k); !ok || !equal(o.m.values[i], v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// listkey is the hash key for lists
+type listkey string
+
+// hashkey returns a comparable value to be used as key in a Go map,
+// so that objects that are equal (see equal) have the same key.
+func hashkey(v any) any {
+	switch t := v.(type) {
+	case Symbol:
+		return Symbol{value: t.value} // without position
+
+	case Float:
+		if !math.IsInf(t.value, 0) && !math.IsNaN(t.value) { // same key as the exact value
+			return hashkey(MakeRational(torat(t)))
+		}
+
+	case Rational:
+		return ratkey(t.value.String())
+
+	case Quoted:
+		return hashkey(t.value)
+
+	case BigInt:
+		return bigkey(t.value.String())
+
+	case List:
+		var sb strings.Builder
+
+		for _, item := range t.items {
+			fmt.Fprintf(&sb, "%#v;", hashkey(item))
+		}
+
+		return listkey(sb.String())
+
+	case Vector:
+		return listkey("[]" + string(hashkey(List{items: t.Items()}).(listkey)))
+
+	case Map: // the entries are sorted, since maps with the same entries are equal in any order
+		entries := make([]string, len(t.Keys()))
+
+		for i, k := range t.Keys() {
+			entries[i] = fmt.Sprintf("%#v=%#v;", hashkey(k), hashkey(t.m.values[i]))
+		}
+
+		slices.Sort(entries)
+		return listkey("{}" + strings.Join(entries, ""))
+	}
+
+	if v != nil && !reflect.TypeOf(v).Comparable() {
+		return listkey(fmt.Sprintf("%T:%v", v, v))
+	}
+
+	return v
+}
+
+// getmap returns the map in the first argument
+func getmap(env *Env, args []any) (Map, []any) {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	m, ok := env.Get(args[0]).(Map)
+	if !ok {
+		invalidType(args[0])
+	}
+
+	return m, args[1:]
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// hash-map key value...
+		//
+		"hash-map": func(env *Env, args []any) any {
+			if len(args)%2 != 0 {
+				return ErrMissing
+			}
+
+			return MakeMap(env.GetList(args)...)
+		},
+
+		//
+		// get map key [default]
+		//
+		"get": func(env *Env, args []any) any {
+			m, args := getmap(env, args)
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			if v, ok := m.Get(env.Get(args[0])); ok {
+				return v
+			}
+
+			if len(args) > 1 {
+				return env.Get(args[1])
+			}
+
+			return Nil
+		},
+
+		//
+		// put map key value... (modifies map)
+		//
+		"put": func(env *Env, args []any) any {
+			m, args := getmap(env, args)
+			if len(args) == 0 || len(args)%2 != 0 {
+				return ErrMissing
+			}
+
+			for i := 0; i < len(args); i += 2 {
+				m.put(env.Get(args[i]), env.Get(args[i+1]))
+			}
+
+			return m
+		},
+
+		//
+		// assoc map key value... (returns a new map)
+		//
+		"assoc": func(env *Env, args []any) any {
+			m, args := getmap(env, args)
+			if len(args) == 0 || len(args)%2 != 0 {
+				return ErrMissing
+			}
+
+			m = m.copy()
+
+			for i := 0; i < len(args); i += 2 {
+				m.put(env.Get(args[i]), env.Get(args[i+1]))
+			}
+
+			return m
+		},
+
+		//
+		// dissoc map key... (returns a new map)
+		//
+		"dissoc": func(env *Env, args []any) any {
+			m, args := getmap(env, args)
+
+			remove := MakeMap()
+			for _, k := range args {
+				remove.put(env.Get(k), True)
+			}
+
+			nm := MakeMap()
+
+			for i, k := range m.Keys() {
+				if _, ok := remove.Get(k); !ok {
+					nm.put(k, m.m.values[i])
+				}
+			}
+
+			return nm
+		},
+
+		//
+		// keys map
+		//
+		"keys": func(env *Env, args []any) any {
+			m, _ := getmap(env, args)
+			return List{items: append([]any(nil), m.Keys()...)}
+		},
+
+		//
+		// values map
+		//
+		"values": func(env *Env, args []any) any {
+			m, _ := getmap(env, args)
+			return List{items: append([]any(nil), m.Values()...)}
+		},
+
+		//
+		// entries map -> ((key value)...)
+		//
+		"entries": func(env *Env, args []any) any {
+			m, _ := getmap(env, args)
+
+			var entries []any
+
+			for i, k := range m.Keys() {
+				entries = append(entries, List{items: []any{k, m.m.values[i]}})
+			}
+
+			return List{items: entries}
+		},
+
+		//
+		// has-key map key
+		//
+		"has-key": func(env *Env, args []any) any {
+			m, args := getmap(env, args)
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			_, ok := m.Get(env.Get(args[0]))
+			return Boolean{value: ok}
+		},
+
+		//
+		// merge map... (returns a new map, the later keys override the earlier ones)
+		//
+		"merge": func(env *Env, args []any) any {
+			nm := MakeMap()
+
+			for _, arg := range args {
+				v := env.Get(arg)
+				if b, ok := v.(Boolean); ok && !b.value { // skip nil
+					continue
+				}
+
+				m, ok := v.(Map)
+				if !ok {
+					return invalidType(v)
+				}
+
+				for i, k := range m.Keys() {
+					nm.put(k, m.m.values[i])
+				}
+			}
+
+			return nm
+		},
+
+		//
+		// maphash function map (calls function with each key and value)
+		//
+		"maphash": func(env *Env, args []any) any {
+			if len(args) != 2 {
+				return ErrMissing
+			}
+
+			f, args := getfunc(env, args)
+			m, _ := getmap(env, args)
+
+			// iterate on a copy, in case the lambda modifies the map
+			keys, values := slices.Clone(m.Keys()), slices.Clone(m.Values())
+
+			for i, k := range keys {
+				apply(env, f, []any{k, values[i]})
+			}
+
+			return Nil
+		},
+	})
+}
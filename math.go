@@ -0,0 +1,273 @@
+package gisp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// maxIntBits is the maximum size (in bits) of the integers created by ash and pow
+const maxIntBits = 1 << 20
+
+// MathModule is the math library: trigonometric, exponential and bitwise functions, and the pi and e constants.
+// It's not loaded by default (see WithModules).
+var MathModule = Module{
+	Name: "math",
+
+	Vars: map[string]any{
+		"pi": Float{value: math.Pi},
+		"e":  Float{value: math.E},
+	},
+
+	Builtins: map[string]Call{
+		"sin":   mathfunc(math.Sin),
+		"cos":   mathfunc(math.Cos),
+		"tan":   mathfunc(math.Tan),
+		"asin":  mathfunc(math.Asin),
+		"acos":  mathfunc(math.Acos),
+		"sinh":  mathfunc(math.Sinh),
+		"cosh":  mathfunc(math.Cosh),
+		"tanh":  mathfunc(math.Tanh),
+		"exp":   mathfunc(math.Exp),
+		"log2":  mathfunc(math.Log2),
+		"log10": mathfunc(math.Log10),
+		"sqrt":  mathfunc(math.Sqrt),
+		"cbrt":  mathfunc(math.Cbrt),
+
+		//
+		// atan y [x]
+		//
+		"atan": func(env *Env, args []any) any {
+			if len(args) > 1 {
+				return Float{value: math.Atan2(getfloat(env, args), getfloat(env, args[1:]))}
+			}
+
+			return Float{value: math.Atan(getfloat(env, args))}
+		},
+
+		//
+		// log x [base]
+		//
+		"log": func(env *Env, args []any) any {
+			if len(args) > 1 {
+				return Float{value: math.Log(getfloat(env, args)) / math.Log(getfloat(env, args[1:]))}
+			}
+
+			return Float{value: math.Log(getfloat(env, args))}
+		},
+
+		//
+		// pow x y (exact for integer x and non negative integer y)
+		//
+		"pow": func(env *Env, args []any) any {
+			if len(args) != 2 {
+				return ErrMissing
+			}
+
+			x, y := getnumber(env, args), getnumber(env, args[1:])
+
+			if numrank(x) == rankInt && numrank(y) == rankInt && sign(y) >= 0 {
+				b := tobig(x)
+
+				// the size of the result is about y * log2(|x|) bits (0, 1 and -1 are always small)
+				if b.CmpAbs(big.NewInt(1)) > 0 && tofloat(y)*math.Log2(math.Abs(tofloat(x))) > maxIntBits {
+					return Throw(MakeError(fmt.Errorf("%w: %v to the power of %v is too large", ErrOutOfRange, x, y)))
+				}
+
+				return MakeBigInt(new(big.Int).Exp(b, tobig(y), nil))
+			}
+
+			return Float{value: math.Pow(tofloat(x), tofloat(y))}
+		},
+
+		//
+		// hypot x y
+		//
+		"hypot": func(env *Env, args []any) any {
+			if len(args) != 2 {
+				return ErrMissing
+			}
+
+			return Float{value: math.Hypot(getfloat(env, args), getfloat(env, args[1:]))}
+		},
+
+		//
+		// abs number
+		//
+		"abs": func(env *Env, args []any) any {
+			switch t := getnumber(env, args).(type) {
+			case Integer:
+				if t.value == math.MinInt64 {
+					return MakeBigInt(new(big.Int).Neg(big.NewInt(t.value)))
+				}
+				if t.value < 0 {
+					return Integer{value: -t.value}
+				}
+
+				return t
+
+			case BigInt:
+				return MakeBigInt(new(big.Int).Abs(t.value))
+
+			case Rational:
+				return MakeRational(new(big.Rat).Abs(t.value))
+
+			case Float:
+				return Float{value: math.Abs(t.value)}
+			}
+
+			return ErrInvalidType
+		},
+
+		//
+		// min number...
+		//
+		"min": minmax(-1),
+
+		//
+		// max number...
+		//
+		"max": minmax(1),
+
+		//
+		// logand integer...
+		//
+		"logand": bitop(func(a, b int64) int64 { return a & b }, (*big.Int).And, -1),
+
+		//
+		// logior integer...
+		//
+		"logior": bitop(func(a, b int64) int64 { return a | b }, (*big.Int).Or, 0),
+
+		//
+		// logxor integer...
+		//
+		"logxor": bitop(func(a, b int64) int64 { return a ^ b }, (*big.Int).Xor, 0),
+
+		//
+		// lognot integer
+		//
+		"lognot": func(env *Env, args []any) any {
+			switch t := getinteger(env, args).(type) {
+			case Integer:
+				return Integer{value: ^t.value}
+
+			case BigInt:
+				return MakeBigInt(new(big.Int).Not(t.value))
+			}
+
+			return ErrInvalidType
+		},
+
+		//
+		// ash integer count (shift left for positive count, right for negative count)
+		//
+		"ash": func(env *Env, args []any) any {
+			if len(args) != 2 {
+				return ErrMissing
+			}
+
+			v := tobig(getinteger(env, args))
+
+			n, ok := getinteger(env, args[1:]).(Integer)
+			if !ok {
+				return invalidType(args[1])
+			}
+
+			if n.value < 0 {
+				shift := uint(v.BitLen()) + 1 // all the bits are shifted out (0, or -1 for negative integers)
+				if n.value > -int64(shift) {
+					shift = uint(-n.value)
+				}
+
+				return MakeBigInt(new(big.Int).Rsh(v, shift))
+			}
+
+			if v.Sign() != 0 && n.value > maxIntBits {
+				return Throw(MakeError(fmt.Errorf("%w: shift count %v", ErrOutOfRange, n.value)))
+			}
+
+			return MakeBigInt(new(big.Int).Lsh(v, uint(n.value)))
+		},
+
+		//
+		// popcount integer (for negative integers it counts the 0 bits)
+		//
+		"popcount": func(env *Env, args []any) any {
+			i, ok := getinteger(env, args).(Integer)
+			if !ok {
+				return invalidType(args[0])
+			}
+
+			if i.value < 0 {
+				return MakeInt(bits.OnesCount64(uint64(^i.value)))
+			}
+
+			return MakeInt(bits.OnesCount64(uint64(i.value)))
+		},
+	},
+}
+
+// getfloat returns the number in the first argument as a float64
+func getfloat(env *Env, args []any) float64 {
+	return tofloat(getnumber(env, args))
+}
+
+// getinteger returns the integer (Integer or BigInt) in the first argument
+func getinteger(env *Env, args []any) any {
+	v := getnumber(env, args)
+	if numrank(v) != rankInt {
+		invalidType(args[0])
+	}
+
+	return v
+}
+
+// mathfunc returns a builtin that calls a float64 function
+func mathfunc(f func(float64) float64) Call {
+	return func(env *Env, args []any) any {
+		return Float{value: f(getfloat(env, args))}
+	}
+}
+
+// minmax returns a builtin that returns the minimum (dir=-1) or maximum (dir=1) of a list of numbers
+func minmax(dir int) Call {
+	return func(env *Env, args []any) any {
+		v := getnumber(env, args)
+
+		for i := range args[1:] {
+			n := getnumber(env, args[i+1:])
+
+			if c, ok := numcmp(n, v); !ok {
+				return Float{value: math.NaN()}
+			} else if c == dir {
+				v = n
+			}
+		}
+
+		return v
+	}
+}
+
+// bitop returns a builtin that executes a bitwise operation on a list of integers
+func bitop(op func(a, b int64) int64, bigop func(z, a, b *big.Int) *big.Int, initial int64) Call {
+	return func(env *Env, args []any) any {
+		var v any = Integer{value: initial}
+
+		for i := range args {
+			n := getinteger(env, args[i:])
+
+			a, ok1 := v.(Integer)
+			b, ok2 := n.(Integer)
+
+			if ok1 && ok2 {
+				v = Integer{value: op(a.value, b.value)}
+			} else {
+				v = MakeBigInt(bigop(new(big.Int), tobig(v), tobig(n)))
+			}
+		}
+
+		return v
+	}
+}
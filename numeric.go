@@ -0,0 +1,416 @@
+package gisp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Rational is the exact rational number type (math/big.Rat), the result of the division of integers that is not whole.
+// Rational values are always normalized: a rational that is an integer is converted to Integer (or BigInt).
+type Rational struct {
+	value *big.Rat
+}
+
+// MakeRational creates a number object from a big.Rat.
+// It returns an integer object (Integer or BigInt) if the value is whole, otherwise a Rational.
+func MakeRational(v *big.Rat) any {
+	if v.IsInt() {
+		return MakeBigInt(new(big.Int).Set(v.Num()))
+	}
+
+	return Rational{value: v}
+}
+
+func (o Rational) String() string { return o.value.String() } // num/denom
+func (o Rational) Value() any     { return o.value }
+func (o Rational) Int() int64     { return new(big.Int).Quo(o.value.Num(), o.value.Denom()).Int64() }
+func (o Rational) Bool() bool     { return true }
+
+func (o Rational) Float() float64 {
+	f, _ := o.value.Float64()
+	return f
+}
+
+func (o Rational) Eq(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c == 0
+}
+
+func (o Rational) Lt(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c < 0
+}
+
+func (o Rational) Leq(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c <= 0
+}
+
+func (o Rational) Gt(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c > 0
+}
+
+func (o Rational) Geq(v any) bool {
+	c, ok := numcmp(o, v)
+	return ok && c >= 0
+}
+
+// ratkey is the hash key for rationals
+type ratkey string
+
+// numeric tower ranks: the result of an operation has the highest rank of its operands
+const (
+	rankNone = iota - 1
+	rankInt
+	rankRat
+	rankFloat
+)
+
+// numrank returns the rank of a number object in the numeric tower, or rankNone if the object is not a number
+func numrank(v any) int {
+	switch v.(type) {
+	case Integer, BigInt:
+		return rankInt
+	case Rational:
+		return rankRat
+	case Float:
+		return rankFloat
+	}
+
+	return rankNone
+}
+
+// tobig converts an integer object to big.Int
+func tobig(v any) *big.Int {
+	switch t := v.(type) {
+	case Integer:
+		return big.NewInt(t.value)
+	case BigInt:
+		return t.value
+	}
+
+	return nil
+}
+
+// torat converts a number object to big.Rat (exactly, for floats that are not infinite or NaN)
+func torat(v any) *big.Rat {
+	switch t := v.(type) {
+	case Integer:
+		return new(big.Rat).SetInt64(t.value)
+	case BigInt:
+		return new(big.Rat).SetInt(t.value)
+	case Rational:
+		return t.value
+	case Float:
+		return new(big.Rat).SetFloat64(t.value)
+	}
+
+	return nil
+}
+
+// tofloat converts a number object to float64
+func tofloat(v any) float64 {
+	if f, ok := v.(CanFloat); ok {
+		return f.Float()
+	}
+
+	return math.NaN()
+}
+
+// numcmp compares two number objects (-1, 0, +1).
+// It returns false if one of the objects is not a number, or is NaN.
+func numcmp(a, b any) (int, bool) {
+	if ia, ok := a.(Integer); ok {
+		if ib, ok := b.(Integer); ok {
+			return cmp64(ia.value, ib.value), true
+		}
+	}
+
+	ra, rb := numrank(a), numrank(b)
+	if ra == rankNone || rb == rankNone {
+		return 0, false
+	}
+
+	if ra == rankFloat || rb == rankFloat {
+		fa, fb := tofloat(a), tofloat(b)
+		if math.IsNaN(fa) || math.IsNaN(fb) {
+			return 0, false
+		}
+
+		if ra == rb || math.IsInf(fa, 0) || math.IsInf(fb, 0) {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+
+			return 0, true
+		}
+	}
+
+	if ra == rankInt && rb == rankInt {
+		return tobig(a).Cmp(tobig(b)), true
+	}
+
+	return torat(a).Cmp(torat(b)), true // exact comparison
+}
+
+func cmp64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// arith executes the arithmetic operation op on two number objects.
+// Any float operand makes the result a float, otherwise the result is exact (integer or rational).
+func arith(op string, a, b any) any {
+	ra, rb := numrank(a), numrank(b)
+	if ra == rankNone {
+		return invalidType(a)
+	}
+	if rb == rankNone {
+		return invalidType(b)
+	}
+
+	rank := ra
+	if rb > rank {
+		rank = rb
+	}
+
+	switch rank {
+	case rankFloat:
+		x, y := tofloat(a), tofloat(b)
+
+		switch op {
+		case "+":
+			return Float{value: x + y}
+		case "-":
+			return Float{value: x - y}
+		case "*":
+			return Float{value: x * y}
+		case "/":
+			return Float{value: x / y}
+		case "%":
+			if y == 0 {
+				return Throw(ErrDivByZero)
+			}
+
+			return Float{value: math.Mod(x, y)}
+		}
+
+	case rankRat:
+		x, y := torat(a), torat(b)
+		r := new(big.Rat)
+
+		switch op {
+		case "+":
+			r.Add(x, y)
+		case "-":
+			r.Sub(x, y)
+		case "*":
+			r.Mul(x, y)
+		case "/", "%":
+			if y.Sign() == 0 {
+				return Throw(ErrDivByZero)
+			}
+
+			r.Quo(x, y)
+
+			if op == "%" { // x - y * truncate(x / y)
+				t := new(big.Rat).SetInt(new(big.Int).Quo(r.Num(), r.Denom()))
+				r.Sub(x, t.Mul(t, y))
+			}
+		}
+
+		return MakeRational(r)
+
+	default:
+		if (op == "/" || op == "%") && sign(b) == 0 {
+			return Throw(ErrDivByZero)
+		}
+
+		if ia, ok := a.(Integer); ok {
+			if ib, ok := b.(Integer); ok && (op != "/" || ia.value%ib.value == 0) {
+				if r, ok := int64op(op, ia.value, ib.value); ok {
+					return Integer{value: r}
+				}
+			}
+		}
+
+		x, y := tobig(a), tobig(b)
+		r := new(big.Int)
+
+		switch op {
+		case "+":
+			r.Add(x, y)
+		case "-":
+			r.Sub(x, y)
+		case "*":
+			r.Mul(x, y)
+		case "/":
+			return MakeRational(new(big.Rat).SetFrac(x, y))
+		case "%":
+			r.Rem(x, y) // truncated, as int64 division
+		}
+
+		return MakeBigInt(r)
+	}
+
+	return invalidType(op)
+}
+
+// sign returns the sign of an integer object
+func sign(v any) int {
+	switch t := v.(type) {
+	case Integer:
+		return cmp64(t.value, 0)
+	case BigInt:
+		return t.value.Sign()
+	}
+
+	return 0
+}
+
+// getnumber returns the number in the first argument
+func getnumber(env *Env, args []any) any {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	v := env.Get(args[0])
+	if numrank(v) == rankNone {
+		invalidType(args[0])
+	}
+
+	return v
+}
+
+// floor returns the largest integer less than or equal to r
+func floor(r *big.Rat) *big.Int {
+	return new(big.Int).Div(r.Num(), r.Denom()) // euclidean division, the denominator is always positive
+}
+
+// rounding returns a builtin that converts a number to an integral value.
+// Exact numbers are converted to integers, floats are rounded to integral floats.
+func rounding(ff func(float64) float64, rf func(*big.Rat) *big.Int) Call {
+	return func(env *Env, args []any) any {
+		switch t := getnumber(env, args).(type) {
+		case Float:
+			return Float{value: ff(t.value)}
+
+		case Rational:
+			return MakeBigInt(rf(t.value))
+
+		default:
+			return t
+		}
+	}
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// numerator number
+		//
+		"numerator": func(env *Env, args []any) any {
+			switch t := getnumber(env, args).(type) {
+			case Rational:
+				return MakeBigInt(new(big.Int).Set(t.value.Num()))
+
+			case Float:
+				return invalidType(t)
+
+			default:
+				return t
+			}
+		},
+
+		//
+		// denominator number
+		//
+		"denominator": func(env *Env, args []any) any {
+			switch t := getnumber(env, args).(type) {
+			case Rational:
+				return MakeBigInt(new(big.Int).Set(t.value.Denom()))
+
+			case Float:
+				return invalidType(t)
+
+			default:
+				return Integer{value: 1}
+			}
+		},
+
+		//
+		// exact->inexact number
+		//
+		"exact->inexact": func(env *Env, args []any) any {
+			return Float{value: tofloat(getnumber(env, args))}
+		},
+
+		//
+		// inexact->exact number
+		//
+		"inexact->exact": func(env *Env, args []any) any {
+			v := getnumber(env, args)
+
+			if f, ok := v.(Float); ok {
+				if math.IsInf(f.value, 0) || math.IsNaN(f.value) {
+					return Throw(MakeError(fmt.Errorf("%w: %v", ErrInvalidType, f)))
+				}
+
+				return MakeRational(torat(f))
+			}
+
+			return v
+		},
+
+		//
+		// floor number
+		//
+		"floor": rounding(math.Floor, floor),
+
+		//
+		// ceiling number
+		//
+		"ceiling": rounding(math.Ceil, func(r *big.Rat) *big.Int {
+			f := floor(new(big.Rat).Neg(r))
+			return f.Neg(f)
+		}),
+
+		//
+		// round number (to even)
+		//
+		"round": rounding(math.RoundToEven, func(r *big.Rat) *big.Int {
+			f := floor(r)
+
+			diff := new(big.Rat).Sub(r, new(big.Rat).SetInt(f))
+
+			switch diff.Cmp(big.NewRat(1, 2)) {
+			case 1:
+				f.Add(f, big.NewInt(1))
+			case 0:
+				if f.Bit(0) == 1 { // odd
+					f.Add(f, big.NewInt(1))
+				}
+			}
+
+			return f
+		}),
+
+		//
+		// truncate number
+		//
+		"truncate": rounding(math.Trunc, func(r *big.Rat) *big.Int {
+			return new(big.Int).Quo(r.Num(), r.Denom())
+		}),
+	})
+}
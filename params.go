@@ -0,0 +1,267 @@
+package gisp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// params is the parsed parameter list of a lambda or macro:
+//
+//	(required... &optional (name default)... &rest name &key (name default)...)
+//
+// Optional and key parameters can be a name, or a (name default) list.
+type params struct {
+	required []any
+	optional []param
+	rest     any
+	keys     []param
+}
+
+type param struct {
+	name any
+	def  any // default value (an expression evaluated when the parameter is missing)
+}
+
+// parseParams parses a parameter list
+func parseParams(l []any) (*params, error) {
+	var p params
+
+	mode := ""
+
+	for i := 0; i < len(l); i++ {
+		if s, ok := l[i].(Symbol); ok && strings.HasPrefix(s.value, "&") {
+			switch s.value {
+			case "&optional", "&key":
+				mode = s.value
+
+			case "&rest", "&body":
+				if i+1 >= len(l) {
+					return nil, fmt.Errorf("%w: missing %v parameter", ErrInvalid, s.value)
+				}
+
+				i++
+				p.rest = l[i]
+				mode = "&rest"
+
+			default:
+				return nil, fmt.Errorf("%w: unknown parameter type %v", ErrInvalid, s.value)
+			}
+
+			continue
+		}
+
+		pp := param{name: l[i], def: Nil}
+
+		if dl, ok := l[i].(List); ok && len(dl.items) > 0 && mode != "" {
+			pp.name = dl.items[0]
+			if len(dl.items) > 1 {
+				pp.def = dl.items[1]
+			}
+		}
+
+		if _, ok := pp.name.(Symbol); !ok {
+			return nil, fmt.Errorf("%w: invalid parameter %v", ErrInvalid, l[i])
+		}
+
+		switch mode {
+		case "":
+			p.required = append(p.required, pp.name)
+
+		case "&optional":
+			p.optional = append(p.optional, pp)
+
+		case "&key":
+			p.keys = append(p.keys, pp)
+
+		default: // after &rest
+			return nil, fmt.Errorf("%w: parameter %v after &rest", ErrInvalid, l[i])
+		}
+	}
+
+	return &p, nil
+}
+
+// arity returns a description of the number of arguments expected
+func (p *params) arity() string {
+	n := len(p.required)
+
+	switch {
+	case p.rest != nil || len(p.keys) > 0:
+		return fmt.Sprintf("at least %v", n)
+
+	case len(p.optional) > 0:
+		return fmt.Sprintf("%v to %v", n, n+len(p.optional))
+	}
+
+	return fmt.Sprint(n)
+}
+
+// bind binds the input values to the parameters in env, evaluating the default values in env.
+// It throws ErrArity (for the named function) if the number of values doesn't match the parameters,
+// or if a keyword argument is unknown.
+func (p *params) bind(env *Env, name string, values []any) {
+	n := len(values)
+
+	arityError := func() {
+		Throw(MakeError(fmt.Errorf("%w: %v expects %v arguments, got %v", ErrArity, name, p.arity(), n)))
+	}
+
+	if len(values) < len(p.required) {
+		arityError()
+	}
+
+	for i, r := range p.required {
+		env.PutLocal(r, values[i])
+	}
+
+	values = values[len(p.required):]
+
+	for _, o := range p.optional {
+		if len(values) > 0 {
+			env.PutLocal(o.name, values[0])
+			values = values[1:]
+		} else {
+			env.PutLocal(o.name, env.Get(o.def))
+		}
+	}
+
+	if p.rest != nil {
+		env.PutLocal(p.rest, List{items: values})
+	}
+
+	if len(p.keys) == 0 {
+		if len(values) > 0 && p.rest == nil {
+			arityError()
+		}
+
+		return
+	}
+
+	if len(values)%2 != 0 {
+		Throw(MakeError(fmt.Errorf("%w: %v expects key value pairs", ErrArity, name)))
+	}
+
+	for i := 0; i < len(values); i += 2 {
+		k, ok := values[i].(Symbol)
+		if !ok || !strings.HasPrefix(k.value, ":") || !p.haskey(k.value[1:]) {
+			Throw(MakeError(fmt.Errorf("%w: unknown key %v for %v", ErrArity, values[i], name)))
+		}
+	}
+
+	for _, k := range p.keys {
+		key := k.name.(Symbol).value
+		value, found := any(nil), false
+
+		for i := len(values) - 2; i >= 0; i -= 2 { // the first occurrence of a key wins
+			if values[i].(Symbol).value[1:] == key {
+				value, found = values[i+1], true
+			}
+		}
+
+		if !found {
+			value = env.Get(k.def)
+		}
+
+		env.PutLocal(k.name, value)
+	}
+}
+
+func (p *params) haskey(name string) bool {
+	for _, k := range p.keys {
+		if k.name.(Symbol).value == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// makeLambda creates a named lambda from a parameter list and a body, with an optional docstring
+func makeLambda(env *Env, name string, plist any, body []any) Lambda {
+	l, ok := plist.(List)
+	if !ok {
+		invalidType(plist)
+	}
+
+	p, err := parseParams(l.items)
+	if err != nil {
+		Throw(MakeError(err))
+	}
+
+	var doc string
+
+	if s, ok := body[0].(String); ok && len(body) > 1 {
+		doc, body = s.value, body[1:]
+	}
+
+	// the body is evaluated in a block, so that (return-from name) exits the function
+	block := slices.ContainsFunc(body, func(v any) bool { return hasReturnFrom(v, name) })
+
+	return Lambda{name: name, doc: doc, args: l.items, params: p, body: body, env: env, block: block}
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// defun name (params) ["doc"] stmt...
+		//
+		"defun": func(env *Env, args []any) any {
+			if len(args) < 3 {
+				return ErrMissing
+			}
+
+			name, ok := args[0].(Symbol)
+			if !ok {
+				return invalidType(args[0])
+			}
+
+			env.Put(name, makeLambda(env, name.value, args[1], args[2:]))
+			return name
+		},
+
+		//
+		// define name value | define (name params...) ["doc"] stmt...
+		//
+		"define": func(env *Env, args []any) any {
+			if len(args) < 2 {
+				return ErrMissing
+			}
+
+			switch t := args[0].(type) {
+			case Symbol:
+				return env.Put(t, env.Get(args[1]))
+
+			case List:
+				if len(t.items) == 0 {
+					return invalidType(t)
+				}
+
+				name, ok := t.items[0].(Symbol)
+				if !ok {
+					return invalidType(t.items[0])
+				}
+
+				env.Put(name, makeLambda(env, name.value, List{items: t.items[1:]}, args[1:]))
+				return name
+			}
+
+			return invalidType(args[0])
+		},
+
+		//
+		// doc function
+		//
+		"doc": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			if l, ok := env.Get(args[0]).(Lambda); ok && l.doc != "" {
+				return String{value: l.doc}
+			}
+
+			return Nil
+		},
+	})
+}
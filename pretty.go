@@ -0,0 +1,433 @@
+package gisp
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultWidth is the line width used by PrettyPrint when the width is not positive
+const defaultWidth = 80
+
+// indentRules are the number of distinguished arguments of the forms with a body.
+// The distinguished arguments stay on the line of the form name, and the body is indented by 2
+// (the other forms are indented as function calls, with the arguments aligned to the first one).
+var indentRules = map[string]int{
+	"lambda":   1,
+	"defun":    2,
+	"defmacro": 2,
+	"define":   1,
+	"let":      1,
+	"when":     1,
+	"unless":   1,
+	"while":    1,
+	"dotimes":  1,
+	"dolist":   1,
+	"for":      1,
+	"block":    1,
+	"case":     1,
+	"begin":    0,
+	"try":      0,
+}
+
+// docKind is the type of a document node
+type docKind int
+
+const (
+	kindText     docKind = iota // a text without new lines
+	kindLine                    // a space, or a new line (followed by the indentation) if the group doesn't fit
+	kindConcat                  // a sequence of documents
+	kindNest                    // increases the indentation of the documents
+	kindAlign                   // sets the indentation to the current column
+	kindGroup                   // the documents are laid out on one line, if they fit
+	kindFill                    // atoms separated by a space, or a new line if the next one doesn't fit
+	kindFillRest                // the atoms after the first one of a fill
+)
+
+// doc is a document for the pretty printer, as in Wadler's "A prettier printer":
+// the layout of each group is flat (all the lines are spaces) if it fits in the remaining width.
+type doc struct {
+	kind   docKind
+	text   string
+	indent int
+	docs   []*doc
+	w      int // the width, if measured
+}
+
+func text(s string) *doc            { return &doc{kind: kindText, text: s} }
+func line() *doc                    { return &doc{kind: kindLine} }
+func concat(docs ...*doc) *doc      { return &doc{kind: kindConcat, docs: docs} }
+func nest(n int, docs ...*doc) *doc { return &doc{kind: kindNest, indent: n, docs: docs} }
+func align(docs ...*doc) *doc       { return &doc{kind: kindAlign, docs: docs} }
+func group(docs ...*doc) *doc       { return &doc{kind: kindGroup, docs: docs} }
+func fill(docs []*doc) *doc         { return &doc{kind: kindFill, docs: docs} }
+
+// joinlines returns the documents separated by lines
+func joinlines(docs []*doc) *doc {
+	l := make([]*doc, 0, 2*len(docs))
+
+	for i, d := range docs {
+		if i > 0 {
+			l = append(l, line())
+		}
+
+		l = append(l, d)
+	}
+
+	return concat(l...)
+}
+
+// width returns the width of a document laid out on one line (measured once)
+func (d *doc) width() int {
+	if d.w > 0 {
+		return d.w
+	}
+
+	var w int
+
+	switch d.kind {
+	case kindText:
+		w = utf8.RuneCountInString(d.text)
+
+	case kindLine:
+		w = 1
+
+	case kindFill, kindFillRest:
+		w = len(d.docs) - 1 // the separators
+	}
+
+	for _, c := range d.docs {
+		w += c.width()
+	}
+
+	d.w = w
+	return w
+}
+
+// layout is a document to render, with its indentation and mode
+type layout struct {
+	indent int
+	flat   bool
+	d      *doc
+}
+
+// push adds the documents to the stack, so that the first one is rendered first
+func push(stack []layout, indent int, flat bool, docs []*doc) []layout {
+	for i := len(docs) - 1; i >= 0; i-- {
+		stack = append(stack, layout{indent: indent, flat: flat, d: docs[i]})
+	}
+
+	return stack
+}
+
+// fits checks if the group laid out flat, followed by the documents in the stack (that is not modified),
+// fit in the width w, up to the first new line
+func fits(w int, group layout, rest []layout) bool {
+	stack := []layout{group}
+
+	for w >= 0 {
+		var l layout
+
+		switch {
+		case len(stack) > 0:
+			l = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+		case len(rest) > 0:
+			l = rest[len(rest)-1]
+			rest = rest[:len(rest)-1]
+
+		default:
+			return true
+		}
+
+		switch l.d.kind {
+		case kindText:
+			w -= l.d.width()
+
+		case kindLine:
+			if !l.flat {
+				return true
+			}
+
+			w--
+
+		case kindFill, kindFillRest:
+			if l.flat {
+				w -= l.d.width()
+				break
+			}
+
+			// the first atom is on this line, the next ones may be on a new line
+			w -= l.d.docs[0].width()
+			if l.d.kind == kindFillRest {
+				w--
+			}
+
+			return w >= 0
+
+		default:
+			stack = push(stack, l.indent, l.flat, l.d.docs)
+		}
+	}
+
+	return false
+}
+
+// render writes the layout of a document that fits the width (if possible)
+func render(sb *strings.Builder, d *doc, width int) {
+	col := 0
+	stack := []layout{{d: d}}
+
+	newline := func(indent int) {
+		sb.WriteString("\n")
+		sb.WriteString(strings.Repeat(" ", indent))
+		col = indent
+	}
+
+	for len(stack) > 0 {
+		l := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		switch l.d.kind {
+		case kindText:
+			sb.WriteString(l.d.text)
+			col += l.d.width()
+
+		case kindLine:
+			if l.flat {
+				sb.WriteString(" ")
+				col++
+			} else {
+				newline(l.indent)
+			}
+
+		case kindConcat:
+			stack = push(stack, l.indent, l.flat, l.d.docs)
+
+		case kindNest:
+			stack = push(stack, l.indent+l.d.indent, l.flat, l.d.docs)
+
+		case kindAlign:
+			stack = push(stack, col, l.flat, l.d.docs)
+
+		case kindGroup:
+			flat := l.flat || fits(width-col, layout{indent: l.indent, flat: true, d: concat(l.d.docs...)}, stack)
+			stack = push(stack, l.indent, flat, l.d.docs)
+
+		case kindFill, kindFillRest:
+			first, rest := l.d.docs[0], l.d.docs[1:]
+
+			if l.d.kind == kindFillRest {
+				if l.flat || col+1+first.width() <= width {
+					sb.WriteString(" ")
+					col++
+				} else {
+					newline(l.indent)
+				}
+			}
+
+			if len(rest) > 0 {
+				stack = append(stack, layout{indent: l.indent, flat: l.flat, d: &doc{kind: kindFillRest, docs: rest}})
+			}
+
+			stack = append(stack, layout{indent: l.indent, flat: true, d: first})
+		}
+	}
+}
+
+// isatom checks if an object is printed on one line
+func isatom(v any) bool {
+	switch t := v.(type) {
+	case List, Vector, Map, Lambda, Macro:
+		return false
+
+	case Quoted:
+		return isatom(t.value)
+
+	case Quasiquoted:
+		return isatom(t.value)
+
+	case Unquoted:
+		return isatom(t.value)
+
+	case Spliced:
+		return isatom(t.value)
+	}
+
+	return true
+}
+
+// prettydoc returns the document for an object
+func prettydoc(v any) *doc {
+	switch t := v.(type) {
+	case List:
+		return listdoc(t.items)
+
+	case Vector:
+		return seqdoc("[", t.Items(), "]")
+
+	case Map:
+		pairs := make([]*doc, len(t.Keys()))
+
+		for i, k := range t.Keys() {
+			pairs[i] = concat(prettydoc(k), text(" "), prettydoc(t.Values()[i]))
+		}
+
+		return group(text("{"), align(joinlines(pairs)), text("}"))
+
+	case Quoted:
+		return concat(text("'"), prettydoc(t.value))
+
+	case Quasiquoted:
+		return concat(text("`"), prettydoc(t.value))
+
+	case Unquoted:
+		return concat(text(","), prettydoc(t.value))
+
+	case Spliced:
+		return concat(text(",@"), prettydoc(t.value))
+
+	case Lambda:
+		return prettydoc(t.source())
+
+	case Macro:
+		return prettydoc(t.source())
+	}
+
+	return text(WriteString(v))
+}
+
+// docs returns the documents for a list of objects
+func docs(items []any) []*doc {
+	l := make([]*doc, len(items))
+
+	for i, v := range items {
+		l[i] = prettydoc(v)
+	}
+
+	return l
+}
+
+// seqdoc returns the document for the items of a sequence, aligned to the first one (and filling the lines, if they are atoms)
+func seqdoc(open string, items []any, close string) *doc {
+	if len(items) == 0 {
+		return text(open + close)
+	}
+
+	if slices.ContainsFunc(items, func(v any) bool { return !isatom(v) }) {
+		return group(text(open), align(joinlines(docs(items))), text(close))
+	}
+
+	return concat(text(open), align(fill(docs(items))), text(close))
+}
+
+// listdoc returns the document for a list, using the indentation rules for the forms
+func listdoc(items []any) *doc {
+	var name string
+
+	if len(items) > 0 {
+		switch t := items[0].(type) {
+		case Symbol:
+			name = t.value
+		case Op:
+			name = t.value
+		case Cond:
+			name = t.value
+		}
+	}
+
+	if name == "" || len(items) == 1 { // data, or call without arguments
+		return seqdoc("(", items, ")")
+	}
+
+	if name == "if" && len(items) > 2 { // the then branch is indented by 4, the else branch by 2
+		l := []*doc{text("(if "), prettydoc(items[1]), nest(4, line(), prettydoc(items[2]))}
+
+		for _, v := range items[3:] {
+			l = append(l, nest(2, line(), prettydoc(v)))
+		}
+
+		return align(group(append(l, text(")"))...))
+	}
+
+	if n, ok := indentRules[name]; ok && len(items) > n+1 {
+		l := []*doc{text("(" + name)}
+
+		for _, v := range items[1 : n+1] {
+			l = append(l, text(" "), prettydoc(v))
+		}
+
+		for _, v := range items[n+1:] {
+			l = append(l, nest(2, line(), prettydoc(v)))
+		}
+
+		return align(group(append(l, text(")"))...))
+	}
+
+	// function call, with the arguments aligned to the first one
+	return concat(text("("+name+" "), seqdoc("", items[1:], ")"))
+}
+
+// PrettyPrint writes the machine readable representation of an object (see Write),
+// with new lines and indentation so that the lines fit in the width (80 if width is not positive), if possible.
+func PrettyPrint(w io.Writer, v any, width int) error {
+	if width <= 0 {
+		width = defaultWidth
+	}
+
+	var sb strings.Builder
+	render(&sb, prettydoc(v), width)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// prettyString returns the pretty printed representation of an object
+func prettyString(v any, width int) string {
+	var sb strings.Builder
+	PrettyPrint(&sb, v, width)
+	return sb.String()
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// pprint obj [width] (writes the pretty printed representation of obj, and a new line)
+		//
+		"pprint": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			v := env.Get(args[0])
+
+			var width int
+			if len(args) > 1 {
+				width = getint(env, args[1:])
+			}
+
+			io.WriteString(env.interp.stdout, prettyString(v, width)+"\n")
+			return v
+		},
+
+		//
+		// pprint-to-string obj [width]
+		//
+		"pprint-to-string": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			v := env.Get(args[0])
+
+			var width int
+			if len(args) > 1 {
+				width = getint(env, args[1:])
+			}
+
+			return String{value: prettyString(v, width)}
+		},
+	})
+}
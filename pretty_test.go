@@ -0,0 +1,36 @@
+package gisp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrettyPrintLarge(t *testing.T) {
+	// (map (lambda (i) (list i (list i i))) (range 20000))
+	items := make([]any, 20000)
+
+	for i := range items {
+		items[i] = List{items: []any{MakeInt(i), List{items: []any{MakeInt(i), MakeInt(i)}}}}
+	}
+
+	v := List{items: items}
+
+	start := time.Now()
+
+	var sb strings.Builder
+	if err := PrettyPrint(&sb, v, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the layout is linear in the size of the object (it took more than 10s when it was quadratic)
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("pretty printed in %v", d)
+	}
+
+	if n := strings.Count(sb.String(), "\n"); n != len(items)-1 {
+		t.Errorf("expected %d lines, got %d", len(items), n+1)
+	}
+
+	checkRead(t, v, sb.String())
+}
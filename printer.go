@@ -0,0 +1,218 @@
+package gisp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Write writes the machine readable representation of an object (as prin1):
+// strings are quoted, characters are written as #\c and floats always have a decimal point or an exponent,
+// so that the output of data objects (numbers, strings, characters, symbols, lists, vectors, maps)
+// can be read back by the Parser.
+func Write(w io.Writer, v any) error {
+	_, err := io.WriteString(w, WriteString(v))
+	return err
+}
+
+// Display writes the human readable representation of an object (as princ and print):
+// strings and characters are written as they are.
+func Display(w io.Writer, v any) error {
+	_, err := io.WriteString(w, DisplayString(v))
+	return err
+}
+
+// WriteString returns the machine readable representation of an object (see Write)
+func WriteString(v any) string {
+	var sb strings.Builder
+	printobj(&sb, v, true)
+	return sb.String()
+}
+
+// DisplayString returns the human readable representation of an object (see Display)
+func DisplayString(v any) string {
+	var sb strings.Builder
+	printobj(&sb, v, false)
+	return sb.String()
+}
+
+// printobj writes the representation of an object, readable (write) or not (display)
+func printobj(sb *strings.Builder, v any, readable bool) {
+	switch t := v.(type) {
+	case String:
+		if readable {
+			sb.WriteString(strconv.Quote(t.value))
+		} else {
+			sb.WriteString(t.value)
+		}
+
+	case Char:
+		if readable {
+			sb.WriteString(`#\` + charname(t.value))
+		} else {
+			sb.WriteRune(t.value)
+		}
+
+	case Float:
+		sb.WriteString(formatFloat(t.value))
+
+	case List:
+		printseq(sb, "(", t.items, ")", readable)
+
+	case Vector:
+		printseq(sb, "[", t.Items(), "]", readable)
+
+	case Map:
+		kv := make([]any, 0, 2*len(t.Keys()))
+
+		for i, k := range t.Keys() {
+			kv = append(kv, k, t.Values()[i])
+		}
+
+		printseq(sb, "{", kv, "}", readable)
+
+	case Quoted:
+		sb.WriteString("'")
+		printobj(sb, t.value, readable)
+
+	case Quasiquoted:
+		sb.WriteString("`")
+		printobj(sb, t.value, readable)
+
+	case Unquoted:
+		sb.WriteString(",")
+		printobj(sb, t.value, readable)
+
+	case Spliced:
+		sb.WriteString(",@")
+		printobj(sb, t.value, readable)
+
+	case Lambda: // the source, always readable
+		printobj(sb, t.source(), true)
+
+	case Macro:
+		printobj(sb, t.source(), true)
+
+	case Error:
+		if readable {
+			fmt.Fprintf(sb, "#<error %q>", t.value.Error())
+		} else {
+			sb.WriteString(t.value.Error())
+		}
+
+	default:
+		fmt.Fprint(sb, v)
+	}
+}
+
+// source returns the source of the function: (defun name (args) ["doc"] body...) or (lambda (args) body...)
+func (o Lambda) source() List {
+	if o.name != "" {
+		items := []any{Symbol{value: "defun"}, Symbol{value: o.name}, List{items: o.args}}
+		if o.doc != "" {
+			items = append(items, String{value: o.doc})
+		}
+
+		return List{items: append(items, o.body...)}
+	}
+
+	return List{items: append([]any{Symbol{value: "lambda"}, List{items: o.args}}, o.body...)}
+}
+
+// source returns the source of the macro: (defmacro name (args) body...)
+func (o Macro) source() List {
+	return List{items: append([]any{Symbol{value: "defmacro"}, Symbol{value: o.name}, List{items: o.args}}, o.body...)}
+}
+
+// printseq writes the items of a sequence, separated by spaces, between the open and close delimiters
+func printseq(sb *strings.Builder, open string, items []any, close string, readable bool) {
+	sb.WriteString(open)
+
+	for i, v := range items {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+
+		printobj(sb, v, readable)
+	}
+
+	sb.WriteString(close)
+}
+
+// formatFloat formats a float so that it's read back as a float (1.0, 1e+21, +inf, -inf, nan)
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+inf"
+
+	case math.IsInf(f, -1):
+		return "-inf"
+
+	case math.IsNaN(f):
+		return "nan"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// prin1 obj (writes the machine readable representation of obj)
+		//
+		"prin1": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			v := env.Get(args[0])
+			Write(env.interp.stdout, v)
+			return v
+		},
+
+		//
+		// princ obj (writes the human readable representation of obj)
+		//
+		"princ": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			v := env.Get(args[0])
+			Display(env.interp.stdout, v)
+			return v
+		},
+
+		//
+		// write-to-string obj
+		//
+		"write-to-string": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			return String{value: WriteString(env.Get(args[0]))}
+		},
+
+		//
+		// read-from-string string (reads the first object in the string)
+		//
+		"read-from-string": func(env *Env, args []any) any {
+			s, _ := getstring(env, args)
+
+			v, err := NewParser(strings.NewReader(s)).Read()
+			if err != nil {
+				return Throw(MakeError(err))
+			}
+
+			return v
+		},
+	})
+}
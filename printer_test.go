@@ -0,0 +1,162 @@
+package gisp
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// valuegen generates data objects from the fuzzer input
+type valuegen struct {
+	data []byte
+}
+
+// byte returns the next input byte (0 at the end of the input)
+func (g *valuegen) byte() byte {
+	if len(g.data) == 0 {
+		return 0
+	}
+
+	b := g.data[0]
+	g.data = g.data[1:]
+	return b
+}
+
+// bytes returns the next n input bytes (padded with 0 at the end of the input)
+func (g *valuegen) bytes(n int) []byte {
+	b := make([]byte, n)
+
+	for i := range b {
+		b[i] = g.byte()
+	}
+
+	return b
+}
+
+func (g *valuegen) int64() int64 {
+	return int64(binary.LittleEndian.Uint64(g.bytes(8)))
+}
+
+const (
+	symbolStart = "abcdxyz"
+	symbolChars = "abcdxyz0123456789-?!*"
+)
+
+// symbol returns a symbol (or a keyword) that doesn't read as a number, a boolean or an operator
+func (g *valuegen) symbol() Symbol {
+	var sb strings.Builder
+
+	if g.byte()%4 == 0 {
+		sb.WriteString(":")
+	}
+
+	sb.WriteByte(symbolStart[int(g.byte())%len(symbolStart)])
+
+	for n := g.byte() % 8; n > 0; n-- {
+		sb.WriteByte(symbolChars[int(g.byte())%len(symbolChars)])
+	}
+
+	return Symbol{value: sb.String()}
+}
+
+// items returns up to 4 values
+func (g *valuegen) items(depth int) []any {
+	l := make([]any, g.byte()%5)
+
+	for i := range l {
+		l[i] = g.value(depth + 1)
+	}
+
+	return l
+}
+
+// value returns a number, string, character, symbol, list, vector or map (up to 4 levels of nesting)
+func (g *valuegen) value(depth int) any {
+	k := g.byte() % 10
+	if depth > 3 && k > 6 {
+		k %= 7
+	}
+
+	switch k {
+	case 0:
+		return Integer{value: g.int64()}
+
+	case 1:
+		v := new(big.Int).SetBytes(g.bytes(int(g.byte()%24) + 1))
+		if g.byte()%2 == 0 {
+			v.Neg(v)
+		}
+
+		return MakeBigInt(v)
+
+	case 2:
+		d := g.int64()
+		if d == 0 {
+			d = 3
+		}
+
+		return MakeRational(big.NewRat(g.int64(), d))
+
+	case 3:
+		f := math.Float64frombits(binary.LittleEndian.Uint64(g.bytes(8)))
+		if math.IsNaN(f) { // nan is not equal to itself
+			f = 0.5
+		}
+
+		return Float{value: f}
+
+	case 4:
+		return String{value: string(g.bytes(int(g.byte() % 16)))}
+
+	case 5:
+		r := rune(binary.LittleEndian.Uint32(append(g.bytes(3), 0)) % (utf8.MaxRune + 1))
+		if !utf8.ValidRune(r) {
+			r = utf8.RuneError
+		}
+
+		return Char{value: r}
+
+	case 6:
+		return g.symbol()
+
+	case 7:
+		return List{items: g.items(depth)}
+
+	case 8:
+		return MakeVector(g.items(depth)...)
+	}
+
+	return MakeMap(g.items(depth)...)
+}
+
+// checkRead checks that the output of the printer reads back as the original value
+func checkRead(t *testing.T, v any, s string) {
+	t.Helper()
+
+	read, err := NewParser(strings.NewReader(s)).Read()
+	if err != nil {
+		t.Fatalf("%q: %v", s, err)
+	}
+
+	if !equal(v, read) || WriteString(read) != WriteString(v) {
+		t.Fatalf("%q read as %v", s, WriteString(read))
+	}
+}
+
+func FuzzWriteRead(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte("\x07\x04\x04abc\"\x06\x00d\x05\x0a\x00\x00"))
+	f.Add([]byte("\x09\x04\x06\x00a\x00\x02\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f\x10\x06\x01x\x04\x05\x05\xe9\x00\x00\x01\x10\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01"))
+	f.Add([]byte("\x08\x03\x07\x02\x04\x04\\\n\t\x01\x00\x03\x03\x00\x00\x00\x00\x00\x00\xf0\x7f\x28"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		g := &valuegen{data: data}
+		v := g.value(0)
+
+		checkRead(t, v, WriteString(v))
+		checkRead(t, v, prettyString(v, int(g.byte()%80)))
+	})
+}
@@ -0,0 +1,175 @@
+package gisp
+
+import (
+	"errors"
+	"fmt"
+	"text/scanner"
+	"unicode"
+)
+
+// ReaderMacro reads the object after a dispatch character (#ch), with the input positioned right after ch.
+// It can read the input with the Parser methods (Next, Peek, Read...) and returns the object read,
+// or nil (not Nil) if there is no object, i.e. for comments.
+type ReaderMacro func(p *Parser, ch rune) (any, error)
+
+// TagFunc creates the object for a tagged literal (#tag value), from the value read after the tag
+type TagFunc func(v any) (any, error)
+
+// readerMacros are the default reader macros of a Parser
+var readerMacros = map[rune]ReaderMacro{
+	'\\': readChar,
+	'|':  readBlockComment,
+	';':  readDatumComment,
+	'.':  readEval,
+	'x':  readRadix,
+	'X':  readRadix,
+	'o':  readRadix,
+	'O':  readRadix,
+	'b':  readRadix,
+	'B':  readRadix,
+}
+
+// readerTags are the default tagged literals of a Parser
+var readerTags = map[string]TagFunc{}
+
+// radixes are the bases of the number literals #x, #o and #b
+var radixes = map[rune]int{'x': 16, 'o': 8, 'b': 2}
+
+// AddReaderMacro registers a reader macro for the dispatch character ch (#ch).
+// Note that it can override the default reader macros (i.e. #\ for characters).
+func (p *Parser) AddReaderMacro(ch rune, m ReaderMacro) {
+	p.macros[ch] = m
+}
+
+// AddTag registers the constructor for the tagged literal #name value.
+// Tags are used for the letters without a reader macro (and for #x, #o and #b, when not followed by a number).
+func (p *Parser) AddTag(name string, f TagFunc) {
+	p.tags[name] = f
+}
+
+// SetEnv sets the environment for read-time evaluation (#.expr), that is disabled if env is nil (the default)
+func (p *Parser) SetEnv(env *Env) {
+	p.env = env
+}
+
+// Read reads the next object from the input
+func (p *Parser) Read() (any, error) {
+	l, err := p.parse(parseDatum, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(l) == 0 {
+		return nil, ErrEOF
+	}
+
+	return l[0], nil
+}
+
+// Next reads the next character from the input
+func (p *Parser) Next() rune {
+	return p.s.Next()
+}
+
+// Peek returns the next character from the input, without reading it
+func (p *Parser) Peek() rune {
+	return p.s.Peek()
+}
+
+// dispatch reads the object after #, with the reader macro for the next character
+// (or as a tagged literal, if there is no reader macro for a letter)
+func (p *Parser) dispatch() (any, error) {
+	pos := p.s.Position
+
+	ch := p.s.Next()
+	if ch == scanner.EOF {
+		return nil, ParseError{Pos: pos, Err: ErrInvalid}
+	}
+
+	m, ok := p.macros[ch]
+	if !ok {
+		if !unicode.IsLetter(ch) {
+			return nil, ParseError{Pos: pos, Err: fmt.Errorf("%w: unknown dispatch character #%c", ErrInvalid, ch)}
+		}
+
+		m = readTagged
+	}
+
+	v, err := m(p, ch)
+	if err != nil && !errors.As(err, new(ParseError)) {
+		err = ParseError{Pos: pos, Err: err}
+	}
+
+	return v, err
+}
+
+// tagged reads the value of the tagged literal #name and calls the tag constructor
+func (p *Parser) tagged(name string) (any, error) {
+	f, ok := p.tags[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown tag #%v", ErrInvalid, name)
+	}
+
+	v, err := p.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return f(v)
+}
+
+// readChar reads a character literal #\c
+func readChar(p *Parser, _ rune) (any, error) {
+	return p.parseChar()
+}
+
+// readBlockComment skips a block comment #| ... |#
+func readBlockComment(p *Parser, _ rune) (any, error) {
+	return nil, p.skipBlock()
+}
+
+// readDatumComment skips the object after #;
+func readDatumComment(p *Parser, _ rune) (any, error) {
+	_, err := p.Read()
+	return nil, err
+}
+
+// readEval evaluates the object after #. at read time
+func readEval(p *Parser, _ rune) (any, error) {
+	if p.env == nil {
+		return nil, fmt.Errorf("%w: read-time evaluation is disabled", ErrPermission)
+	}
+
+	v, err := p.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return EvalE(p.env, v)
+}
+
+// readRadix reads a number literal in base 16 (#x1F), 8 (#o17) or 2 (#b101)
+func readRadix(p *Parser, ch rune) (any, error) {
+	st := p.scanToken()
+
+	if n, ok := parseInteger(st, radixes[unicode.ToLower(ch)]); ok {
+		return n, nil
+	}
+
+	if name := string(ch) + st; p.tags[name] != nil {
+		return p.tagged(name)
+	}
+
+	return nil, fmt.Errorf("%w: invalid number #%c%v", ErrInvalid, ch, st)
+}
+
+// readTagged reads a tagged literal #name value
+func readTagged(p *Parser, ch rune) (any, error) {
+	name := string(ch)
+
+	for !p.SepNext() && p.s.Peek() != '"' {
+		name += string(p.s.Next())
+	}
+
+	return p.tagged(name)
+}
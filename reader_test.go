@@ -0,0 +1,100 @@
+package gisp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReaderMacro(t *testing.T) {
+	p := NewParser(strings.NewReader(`(1 #!rest of the line
+ #q (a b) 2)`))
+
+	// #! reads the rest of the line as a string
+	p.AddReaderMacro('!', func(p *Parser, ch rune) (any, error) {
+		var sb strings.Builder
+
+		for c := p.Peek(); c != '\n' && c >= 0; c = p.Peek() {
+			sb.WriteRune(p.Next())
+		}
+
+		return String{value: sb.String()}, nil
+	})
+
+	// #q obj reads as (quote obj)
+	p.AddReaderMacro('q', func(p *Parser, ch rune) (any, error) {
+		v, err := p.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		return Quoted{value: v}, nil
+	})
+
+	v, err := p.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := List{items: []any{
+		MakeInt(1),
+		String{value: "rest of the line"},
+		Quoted{value: List{items: []any{Symbol{value: "a"}, Symbol{value: "b"}}}},
+		MakeInt(2),
+	}}
+
+	if !equal(v, expected) {
+		t.Errorf("expected %v, got %v", WriteString(expected), WriteString(v))
+	}
+
+	// the reader macros are registered for a single parser
+	if v, err := NewParser(strings.NewReader(`#!x`)).Read(); err == nil {
+		t.Errorf("expected an error for #!, got %v", v)
+	}
+}
+
+func TestReaderTag(t *testing.T) {
+	errNotString := errors.New("not a string")
+
+	p := NewParser(strings.NewReader(`[#upper "abc" #upper 1]`))
+
+	p.AddTag("upper", func(v any) (any, error) {
+		s, ok := v.(String)
+		if !ok {
+			return nil, errNotString
+		}
+
+		return String{value: strings.ToUpper(s.value)}, nil
+	})
+
+	if v, err := p.Read(); !errors.Is(err, errNotString) {
+		t.Errorf("expected %v, got %v %v", errNotString, v, err)
+	}
+
+	p = NewParser(strings.NewReader(`[#point [1 2] #upper "abc"]`))
+
+	p.AddTag("upper", func(v any) (any, error) {
+		return String{value: strings.ToUpper(v.(String).value)}, nil
+	})
+
+	p.AddTag("point", func(v any) (any, error) {
+		items := v.(Vector).Items()
+		return MakeMap(Symbol{value: ":x"}, items[0], Symbol{value: ":y"}, items[1]), nil
+	})
+
+	v, err := p.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := MakeVector(MakeMap(Symbol{value: ":x"}, MakeInt(1), Symbol{value: ":y"}, MakeInt(2)), String{value: "ABC"})
+
+	if !equal(v, expected) {
+		t.Errorf("expected %v, got %v", WriteString(expected), WriteString(v))
+	}
+
+	// the tags are registered for a single parser
+	if v, err := NewParser(strings.NewReader(`#upper "abc"`)).Read(); err == nil {
+		t.Errorf("expected an error for #upper, got %v", v)
+	}
+}
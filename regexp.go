@@ -0,0 +1,213 @@
+package gisp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxRegexps is the maximum number of compiled patterns cached by an interpreter
+const maxRegexps = 256
+
+// Regexp is the compiled regular expression type (see regexp.Regexp for the syntax)
+type Regexp struct {
+	value *regexp.Regexp
+}
+
+func (o Regexp) String() string { return fmt.Sprintf("#regex %q", o.value.String()) }
+func (o Regexp) Value() any     { return o.value }
+func (o Regexp) Bool() bool     { return true }
+
+// regexp returns the compiled pattern, from the interpreter cache if available
+func (it *Interpreter) regexp(pattern string) (*regexp.Regexp, error) {
+	it.remu.Lock()
+	defer it.remu.Unlock()
+
+	if re, ok := it.regexps[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if it.regexps == nil || len(it.regexps) >= maxRegexps {
+		it.regexps = map[string]*regexp.Regexp{}
+	}
+
+	it.regexps[pattern] = re
+	return re, nil
+}
+
+// getregexp returns the regular expression in the first argument (a Regexp or a String pattern)
+func getregexp(env *Env, args []any) (*regexp.Regexp, []any) {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	switch t := env.Get(args[0]).(type) {
+	case Regexp:
+		return t.value, args[1:]
+
+	case String:
+		re, err := env.interp.regexp(t.value)
+		if err != nil {
+			Throw(MakeError(err))
+		}
+
+		return re, args[1:]
+	}
+
+	invalidType(args[0])
+	return nil, nil
+}
+
+// submatch returns a match as a String if the regular expression has no capture groups,
+// or as a List with the match and the capture groups (nil for groups that didn't match)
+func submatch(re *regexp.Regexp, s string, loc []int) any {
+	if re.NumSubexp() == 0 {
+		return String{value: s[loc[0]:loc[1]]}
+	}
+
+	items := make([]any, 0, len(loc)/2)
+
+	for i := 0; i < len(loc); i += 2 {
+		if loc[i] < 0 {
+			items = append(items, Nil)
+		} else {
+			items = append(items, String{value: s[loc[i]:loc[i+1]]})
+		}
+	}
+
+	return List{items: items}
+}
+
+func init() {
+	// #regex "pattern" (the printed form of Regexp)
+	readerTags["regex"] = func(v any) (any, error) {
+		s, ok := v.(String)
+		if !ok {
+			return nil, fmt.Errorf("%w: #regex %v", ErrInvalidType, v)
+		}
+
+		re, err := regexp.Compile(s.value)
+		if err != nil {
+			return nil, err
+		}
+
+		return Regexp{value: re}, nil
+	}
+
+	addBuiltins(map[string]Call{
+		//
+		// regex pattern
+		//
+		"regex": func(env *Env, args []any) any {
+			re, _ := getregexp(env, args)
+			return Regexp{value: re}
+		},
+
+		//
+		// re-match regex string
+		//
+		"re-match": func(env *Env, args []any) any {
+			re, args := getregexp(env, args)
+			s, _ := getstring(env, args)
+			return Boolean{value: re.MatchString(s)}
+		},
+
+		//
+		// re-find regex string (the match, or the list of capture groups if the regex has groups)
+		//
+		"re-find": func(env *Env, args []any) any {
+			re, args := getregexp(env, args)
+			s, _ := getstring(env, args)
+
+			loc := re.FindStringSubmatchIndex(s)
+			if loc == nil {
+				return Nil
+			}
+
+			return submatch(re, s, loc)
+		},
+
+		//
+		// re-find-all regex string [count]
+		//
+		"re-find-all": func(env *Env, args []any) any {
+			re, args := getregexp(env, args)
+			s, args := getstring(env, args)
+
+			n := -1
+			if len(args) > 0 {
+				n = getint(env, args)
+			}
+
+			var items []any
+
+			for _, loc := range re.FindAllStringSubmatchIndex(s, n) {
+				items = append(items, submatch(re, s, loc))
+			}
+
+			return List{items: items}
+		},
+
+		//
+		// re-replace regex string replacement
+		//
+		// The replacement can be a string (with $1 or ${name} for the capture groups)
+		// or a lambda, called with the match (as returned by re-find) that returns the replacement string.
+		//
+		"re-replace": func(env *Env, args []any) any {
+			re, args := getregexp(env, args)
+			s, args := getstring(env, args)
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			switch t := env.Get(args[0]).(type) {
+			case String:
+				return String{value: re.ReplaceAllString(s, t.value)}
+
+			case Lambda:
+				var sb strings.Builder
+
+				last := 0
+
+				for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+					sb.WriteString(s[last:loc[0]])
+
+					switch r := CallLambda(t, env, []any{Quoted{value: submatch(re, s, loc)}}).(type) {
+					case String:
+						sb.WriteString(r.value)
+					default:
+						sb.WriteString(fmt.Sprint(r))
+					}
+
+					last = loc[1]
+				}
+
+				sb.WriteString(s[last:])
+				return String{value: sb.String()}
+			}
+
+			return invalidType(args[0])
+		},
+
+		//
+		// re-split regex string [count]
+		//
+		"re-split": func(env *Env, args []any) any {
+			re, args := getregexp(env, args)
+			s, args := getstring(env, args)
+
+			n := -1
+			if len(args) > 0 {
+				n = getint(env, args)
+			}
+
+			return stringlist(re.Split(s, n))
+		},
+	})
+}
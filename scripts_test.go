@@ -0,0 +1,80 @@
+package gisp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// assert is the builtin used by the test scripts to check the results:
+// (assert name check...) fails the test for each check that doesn't evaluate to true.
+func assert(t *testing.T) Call {
+	return func(env *Env, args []any) any {
+		if len(args) == 0 {
+			return ErrMissing
+		}
+
+		name := env.Get(args[0])
+
+		for i, check := range args[1:] {
+			if v := env.Get(check); v != True {
+				t.Errorf("%v: check %d %v returned %v", name, i+1, check, v)
+			}
+		}
+
+		return True
+	}
+}
+
+// run parses and evaluates a program in a new interpreter (with the assert builtin), returning the output
+func run(t *testing.T, src string, opts ...Option) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	it := New(append([]Option{WithStdout(&out), WithModules(MathModule)}, opts...)...)
+	it.AddBuiltin("assert", assert(t))
+
+	p := NewParser(strings.NewReader(src))
+	p.SetEnv(it.Env())
+
+	l, err := p.Parse()
+	if err != nil {
+		return out.String(), err
+	}
+
+	for _, v := range l {
+		if _, err := it.EvalE(v); err != nil {
+			return out.String(), err
+		}
+	}
+
+	return out.String(), nil
+}
+
+// TestScripts runs the test scripts in tests/
+func TestScripts(t *testing.T) {
+	files, err := filepath.Glob("tests/*.gisp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no test scripts")
+	}
+
+	for _, name := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(name), ".gisp"), func(t *testing.T) {
+			src, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out, err := run(t, string(src)); err != nil {
+				t.Fatalf("%v\n%v", err, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package gisp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// getstring returns the string in the first argument
+func getstring(env *Env, args []any) (string, []any) {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	s, ok := env.Get(args[0]).(String)
+	if !ok {
+		invalidType(args[0])
+	}
+
+	return s.value, args[1:]
+}
+
+// getint returns the integer in the first argument, as an int
+func getint(env *Env, args []any) int {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	i, ok := env.Get(args[0]).(Integer)
+	if !ok {
+		invalidType(args[0])
+	}
+
+	return int(i.value)
+}
+
+// strfunc returns a builtin that calls a string function
+func strfunc(f func(string) string) Call {
+	return func(env *Env, args []any) any {
+		s, _ := getstring(env, args)
+		return String{value: f(s)}
+	}
+}
+
+// stringlist converts a list of strings to a List of String objects
+func stringlist(l []string) List {
+	items := make([]any, len(l))
+
+	for i, s := range l {
+		items[i] = String{value: s}
+	}
+
+	return List{items: items}
+}
+
+// parseInteger parses an integer in the specified base, with optional _ separators between digits.
+// In base 0 the base is 10, or the one selected by a 0x, 0o or 0b prefix.
+func parseInteger(s string, base int) (any, bool) {
+	var sign string
+
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		sign, s = s[:1], s[1:]
+	}
+
+	if base == 0 {
+		base = 10
+
+		if len(s) > 2 && s[0] == '0' {
+			switch s[1] {
+			case 'x', 'X':
+				base = 16
+			case 'o', 'O':
+				base = 8
+			case 'b', 'B':
+				base = 2
+			}
+
+			if base != 10 {
+				s = s[2:]
+			}
+		}
+	}
+
+	if s == "" || s[0] == '_' || s[len(s)-1] == '_' || strings.Contains(s, "__") {
+		return nil, false
+	}
+
+	s = sign + strings.ReplaceAll(s, "_", "")
+
+	if i, err := strconv.ParseInt(s, base, 64); err == nil {
+		return Integer{value: i}, true
+	}
+
+	if b, ok := new(big.Int).SetString(s, base); ok {
+		return MakeBigInt(b), true
+	}
+
+	return nil, false
+}
+
+// parseNumber parses the string representation of a number in the specified base.
+// In base 10 it accepts all the number literals: integers (with 0x, 0o or 0b prefix and _ separators),
+// rationals (3/4), floats (1.5, 1e10), +inf, -inf and nan.
+func parseNumber(s string, base int) (any, bool) {
+	if base != 10 {
+		return parseInteger(s, base)
+	}
+
+	if n, ok := parseInteger(s, 0); ok {
+		return n, true
+	}
+
+	switch strings.ToLower(s) {
+	case "+inf", "inf":
+		return Float{value: math.Inf(1)}, true
+
+	case "-inf":
+		return Float{value: math.Inf(-1)}, true
+
+	case "nan", "+nan", "-nan":
+		return Float{value: math.NaN()}, true
+	}
+
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, ok := parseInteger(num, 0)
+		if !ok || den == "" || den[0] == '+' || den[0] == '-' {
+			return nil, false
+		}
+
+		d, ok := parseInteger(den, 0)
+		if !ok || sign(d) == 0 {
+			return nil, false
+		}
+
+		return MakeRational(new(big.Rat).SetFrac(tobig(n), tobig(d))), true
+	}
+
+	if strings.ContainsAny(strings.ToLower(s), "in") { // only the spellings above for infinity and NaN
+		return nil, false
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return Float{value: f}, true
+	}
+
+	return nil, false
+}
+
+// maxRepeatLen is the maximum length (in bytes) of the strings created by repeat
+const maxRepeatLen = 1 << 28
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// substring string start [end] (rune indices)
+		//
+		"substring": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+			runes := []rune(s)
+
+			start := getindex(env, args, len(runes)+1)
+			end := len(runes)
+
+			if len(args) > 1 {
+				end = getindex(env, args[1:], len(runes)+1)
+			}
+
+			if end < start {
+				return Throw(MakeError(fmt.Errorf("%w: %v", ErrOutOfRange, end)))
+			}
+
+			return String{value: string(runes[start:end])}
+		},
+
+		//
+		// string-length string (number of runes)
+		//
+		"string-length": func(env *Env, args []any) any {
+			s, _ := getstring(env, args)
+			return MakeInt(utf8.RuneCountInString(s))
+		},
+
+		//
+		// split string [separator] (split on white spaces if there is no separator)
+		//
+		"split": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+
+			if len(args) == 0 {
+				return stringlist(strings.Fields(s))
+			}
+
+			sep, _ := getstring(env, args)
+			return stringlist(strings.Split(s, sep))
+		},
+
+		//
+		// join list [separator]
+		//
+		"join": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			var items []any
+
+			switch t := env.Get(args[0]).(type) {
+			case List:
+				items = t.items
+
+			case Vector:
+				items = t.Items()
+
+			default:
+				return invalidType(args[0])
+			}
+
+			var sep string
+			if len(args) > 1 {
+				sep, _ = getstring(env, args[1:])
+			}
+
+			parts := make([]string, len(items))
+
+			for i, v := range items {
+				parts[i] = fmt.Sprint(v)
+			}
+
+			return String{value: strings.Join(parts, sep)}
+		},
+
+		//
+		// trim string [cutset] (trim white spaces if there is no cutset)
+		//
+		"trim": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+
+			if len(args) == 0 {
+				return String{value: strings.TrimSpace(s)}
+			}
+
+			cutset, _ := getstring(env, args)
+			return String{value: strings.Trim(s, cutset)}
+		},
+
+		//
+		// upcase string
+		//
+		"upcase": strfunc(strings.ToUpper),
+
+		//
+		// downcase string
+		//
+		"downcase": strfunc(strings.ToLower),
+
+		//
+		// replace string old new [count] (replace all if there is no count)
+		//
+		"replace": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+			if len(args) < 2 {
+				return ErrMissing
+			}
+
+			sold, _ := getstring(env, args)
+			snew, _ := getstring(env, args[1:])
+
+			n := -1
+			if len(args) > 2 {
+				n = getint(env, args[2:])
+			}
+
+			return String{value: strings.Replace(s, sold, snew, n)}
+		},
+
+		//
+		// starts-with string prefix
+		//
+		"starts-with": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+			prefix, _ := getstring(env, args)
+			return Boolean{value: strings.HasPrefix(s, prefix)}
+		},
+
+		//
+		// ends-with string suffix
+		//
+		"ends-with": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+			suffix, _ := getstring(env, args)
+			return Boolean{value: strings.HasSuffix(s, suffix)}
+		},
+
+		//
+		// string->number string [base] (nil if the string is not a number)
+		//
+		"string->number": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+
+			base := 10
+			if len(args) > 0 {
+				base = getint(env, args)
+			}
+
+			if base < 2 || base > 36 {
+				return Throw(MakeError(fmt.Errorf("%w: base %v", ErrOutOfRange, base)))
+			}
+
+			if n, ok := parseNumber(strings.TrimSpace(s), base); ok {
+				return n
+			}
+
+			return Nil
+		},
+
+		//
+		// number->string number [base]
+		//
+		"number->string": func(env *Env, args []any) any {
+			n := getnumber(env, args)
+
+			base := 10
+			if len(args) > 1 {
+				base = getint(env, args[1:])
+			}
+
+			if base < 2 || base > 36 {
+				return Throw(MakeError(fmt.Errorf("%w: base %v", ErrOutOfRange, base)))
+			}
+
+			switch t := n.(type) {
+			case Integer:
+				return String{value: strconv.FormatInt(t.value, base)}
+
+			case BigInt:
+				return String{value: t.value.Text(base)}
+			}
+
+			if base != 10 {
+				return invalidType(n)
+			}
+
+			return String{value: fmt.Sprint(n)}
+		},
+
+		//
+		// string->list string (list of chars)
+		//
+		"string->list": func(env *Env, args []any) any {
+			s, _ := getstring(env, args)
+
+			var items []any
+
+			for _, r := range s {
+				items = append(items, Char{value: r})
+			}
+
+			return List{items: items}
+		},
+
+		//
+		// repeat string count
+		//
+		"repeat": func(env *Env, args []any) any {
+			s, args := getstring(env, args)
+
+			n := getint(env, args)
+			if n < 0 || (n > 0 && len(s) > maxRepeatLen/n) {
+				return Throw(MakeError(fmt.Errorf("%w: %v", ErrOutOfRange, n)))
+			}
+
+			return String{value: strings.Repeat(s, n)}
+		},
+	})
+}
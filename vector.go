@@ -0,0 +1,185 @@
+package gisp
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Vector is the vector (array) type, with constant time indexing.
+// A Vector is a reference type: vset! and vpush modify the vector in place, while subvec returns a new vector.
+type Vector struct {
+	items *[]any
+}
+
+// MakeVector creates a Vector object from a list of objects
+func MakeVector(items ...any) Vector {
+	v := slices.Clone(items)
+	return Vector{items: &v}
+}
+
+func (o Vector) String() string { return DisplayString(o) } // [a b c]
+
+func (o Vector) Value() any { return o.Items() }
+
+func (o Vector) Bool() bool { return o.Len() > 0 }
+
+// Len returns the number of elements in the vector
+func (o Vector) Len() int {
+	return len(o.Items())
+}
+
+// Items returns the vector elements
+func (o Vector) Items() []any {
+	if o.items == nil {
+		return nil
+	}
+
+	return *o.items
+}
+
+// Item returns the element at index i (nil if out of range)
+func (o Vector) Item(i int) any {
+	if i < 0 || i >= o.Len() {
+		return nil
+	}
+
+	return (*o.items)[i]
+}
+
+// getvector returns the vector in the first argument
+func getvector(env *Env, args []any) (Vector, []any) {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	v, ok := env.Get(args[0]).(Vector)
+	if !ok {
+		invalidType(args[0])
+	}
+
+	return v, args[1:]
+}
+
+// getindex returns the index in the first argument, checking that is an integer within [0, n)
+func getindex(env *Env, args []any, n int) int {
+	if len(args) == 0 {
+		Throw(ErrMissing)
+	}
+
+	var i int64
+
+	switch t := env.Get(args[0]).(type) {
+	case Integer:
+		i = t.value
+
+	case BigInt: // always out of range
+		Throw(MakeError(fmt.Errorf("%w: %v", ErrOutOfRange, t)))
+
+	default:
+		invalidType(args[0])
+	}
+
+	if i < 0 || i >= int64(n) {
+		Throw(MakeError(fmt.Errorf("%w: %v", ErrOutOfRange, i)))
+	}
+
+	return int(i)
+}
+
+func init() {
+	addBuiltins(map[string]Call{
+		//
+		// vector value...
+		//
+		"vector": func(env *Env, args []any) any {
+			return MakeVector(env.GetList(args)...)
+		},
+
+		//
+		// vref vector index
+		//
+		"vref": func(env *Env, args []any) any {
+			v, args := getvector(env, args)
+			return v.Item(getindex(env, args, v.Len()))
+		},
+
+		//
+		// vset! vector index value (modifies vector)
+		//
+		"vset!": func(env *Env, args []any) any {
+			v, args := getvector(env, args)
+			if len(args) != 2 {
+				return ErrMissing
+			}
+
+			i := getindex(env, args, v.Len())
+			(*v.items)[i] = env.Get(args[1])
+			return v
+		},
+
+		//
+		// vpush vector value... (modifies vector)
+		//
+		"vpush": func(env *Env, args []any) any {
+			v, args := getvector(env, args)
+			if v.items == nil {
+				return invalidType(v)
+			}
+
+			*v.items = append(*v.items, env.GetList(args)...)
+			return v
+		},
+
+		//
+		// vlen vector
+		//
+		"vlen": func(env *Env, args []any) any {
+			v, _ := getvector(env, args)
+			return MakeInt(v.Len())
+		},
+
+		//
+		// subvec vector start [end] (returns a new vector)
+		//
+		"subvec": func(env *Env, args []any) any {
+			v, args := getvector(env, args)
+
+			start := getindex(env, args, v.Len()+1)
+			end := v.Len()
+
+			if len(args) > 1 {
+				end = getindex(env, args[1:], v.Len()+1)
+			}
+
+			if end < start {
+				return Throw(MakeError(fmt.Errorf("%w: %v", ErrOutOfRange, end)))
+			}
+
+			return MakeVector(v.Items()[start:end]...)
+		},
+
+		//
+		// list->vector list
+		//
+		"list->vector": func(env *Env, args []any) any {
+			if len(args) == 0 {
+				return ErrMissing
+			}
+
+			l, ok := env.Get(args[0]).(List)
+			if !ok {
+				return invalidType(args[0])
+			}
+
+			return MakeVector(l.items...)
+		},
+
+		//
+		// vector->list vector
+		//
+		"vector->list": func(env *Env, args []any) any {
+			v, _ := getvector(env, args)
+			return List{items: slices.Clone(v.Items())}
+		},
+	})
+}